@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	outcomeSucceeded = "succeeded"
+	outcomeUpToDate  = "up-to-date"
+	outcomeAborted   = "aborted"
+	outcomeFailed    = "failed"
+	// outcomeReviewed is a --since-last-upgrade run, which only reviews notes
+	outcomeReviewed = "reviewed"
+)
+
+// auditEntry is a single line of the audit log. It must never carry chart values, which can hold secrets, so flags
+// are recorded by name only.
+type auditEntry struct {
+	RunID                   string    `json:"runId"`
+	Time                    time.Time `json:"time"`
+	User                    string    `json:"user"`
+	Release                 string    `json:"release,omitempty"`
+	Namespace               string    `json:"namespace,omitempty"`
+	FromVersion             string    `json:"fromVersion,omitempty"`
+	ToVersion               string    `json:"toVersion,omitempty"`
+	DryRun                  bool      `json:"dryRun"`
+	Flags                   []string  `json:"flags"`
+	AcknowledgedKnownIssues []string  `json:"acknowledgedKnownIssues"`
+	Outcome                 string    `json:"outcome"`
+	Error                   string    `json:"error,omitempty"`
+}
+
+func newAuditEntry(ctx *cli.Context, now time.Time) auditEntry {
+	entry := auditEntry{
+		Time:                    now.UTC(),
+		DryRun:                  ctx.Bool("dry-run"),
+		Flags:                   ctx.FlagNames(),
+		AcknowledgedKnownIssues: []string{},
+	}
+	if currentUser, err := user.Current(); err == nil {
+		entry.User = currentUser.Username
+	}
+	return entry
+}
+
+// finish records the outcome of the run. Runs that returned without an error or an outcome were stopped by the user.
+// Runs missing release notes keep their outcome, the missing notes are recorded as the error.
+func (a *auditEntry) finish(err error) {
+	var incomplete incompleteNotesError
+	if errors.As(err, &incomplete) && a.Outcome != "" {
+		a.Error = err.Error()
+		return
+	}
+	if err != nil {
+		a.Outcome = outcomeFailed
+		a.Error = err.Error()
+		return
+	}
+	if a.Outcome == "" {
+		a.Outcome = outcomeAborted
+	}
+}
+
+func appendAuditEntry(path string, entry auditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
+)
+
+// defaultClusterValuesKey is the ConfigMap or Secret key the values are read from when no key is given.
+const defaultClusterValuesKey = "values.yaml"
+
+// clusterValuesRef is a key of a ConfigMap or Secret holding values YAML.
+type clusterValuesRef struct {
+	kind      string
+	namespace string
+	name      string
+	key       string
+}
+
+func (r clusterValuesRef) String() string {
+	return fmt.Sprintf("%s [%s/%s:%s]", r.kind, r.namespace, r.name, r.key)
+}
+
+// parseClusterValuesRef parses namespace/name or namespace/name:key, key defaulting to defaultClusterValuesKey.
+func parseClusterValuesRef(kind, ref string) (clusterValuesRef, error) {
+	parsed := clusterValuesRef{kind: kind, key: defaultClusterValuesKey}
+	namespacedName, key, hasKey := strings.Cut(strings.TrimSpace(ref), ":")
+	if hasKey {
+		parsed.key = key
+	}
+	namespace, name, _ := strings.Cut(namespacedName, "/")
+	parsed.namespace, parsed.name = namespace, name
+	if namespace == "" || name == "" || strings.Contains(name, "/") || parsed.key == "" {
+		return clusterValuesRef{}, fmt.Errorf("invalid %s reference [%s], must be namespace/name or namespace/name:key", kind, ref)
+	}
+	return parsed, nil
+}
+
+// readClusterValues reads the values YAML of every ConfigMap and then every Secret and deep merges them in order,
+// the same way several values files are merged. It returns nil when there are none.
+func readClusterValues(execer helmExecer, configMaps, secrets []string) (map[string]interface{}, error) {
+	var refs []clusterValuesRef
+	for _, ref := range configMaps {
+		parsed, err := parseClusterValuesRef("configmap", ref)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, parsed)
+	}
+	for _, ref := range secrets {
+		parsed, err := parseClusterValuesRef("secret", ref)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, parsed)
+	}
+	if len(refs) == 0 {
+		return nil, nil
+	}
+
+	merged := map[string]interface{}{}
+	names := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		data, err := readClusterValuesData(execer, ref)
+		if err != nil {
+			return nil, err
+		}
+		var values map[string]interface{}
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("failed to parse the values in %s: %w", ref, err)
+		}
+		merged = mergeValues(merged, values)
+		names = append(names, ref.String())
+	}
+	fmt.Println(i18n.T(i18n.UsingClusterValues, strings.Join(names, ", ")))
+	return merged, nil
+}
+
+func readClusterValuesData(execer helmExecer, ref clusterValuesRef) ([]byte, error) {
+	if ref.kind == "secret" {
+		secret, err := execer.Secret(ref.namespace, ref.name)
+		if err != nil {
+			return nil, err
+		}
+		data, ok := secret.Data[ref.key]
+		if !ok {
+			return nil, fmt.Errorf("%s has no key [%s]", ref, ref.key)
+		}
+		return data, nil
+	}
+
+	configMap, err := execer.ConfigMap(ref.namespace, ref.name)
+	if err != nil {
+		return nil, err
+	}
+	if data, ok := configMap.Data[ref.key]; ok {
+		return []byte(data), nil
+	}
+	if data, ok := configMap.BinaryData[ref.key]; ok {
+		return data, nil
+	}
+	return nil, fmt.Errorf("%s has no key [%s]", ref, ref.key)
+}
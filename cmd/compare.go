@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/rmweir/rancher-upgrader/internal/diff"
+	"github.com/rmweir/rancher-upgrader/internal/helm"
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
+	"github.com/urfave/cli/v2"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+type CompareActionClient struct {
+	helmExecer helmExecer
+}
+
+func CompareCommand() *cli.Command {
+	flags := append(repoFlags(),
+		&cli.StringFlag{
+			Name:     "from",
+			Usage:    "Rancher chart version to compare from",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "to",
+			Usage:    "Rancher chart version to compare to",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "output",
+			Usage: "Output format, one of: text, json",
+			Value: "text",
+		},
+	)
+
+	c := &CompareActionClient{}
+	return &cli.Command{
+		Name:   "compare",
+		Usage:  "Compare the chart metadata and default values of two rancher chart versions without a cluster",
+		Action: c.Compare,
+		Flags:  flags,
+	}
+}
+
+func (c *CompareActionClient) Init(opts helm.Options) error {
+	client, err := helm.NewChartClient(opts)
+	if err != nil {
+		return err
+	}
+	c.helmExecer = client
+	return nil
+}
+
+// chartComparison is the JSON output of the compare command.
+type chartComparison struct {
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Metadata []diff.Change `json:"metadata"`
+	Values   []diff.Change `json:"values"`
+}
+
+func (c *CompareActionClient) Compare(ctx *cli.Context) error {
+	output := ctx.String("output")
+	if output != "text" && output != "json" {
+		return fmt.Errorf("unsupported output format [%s], must be one of: text, json", output)
+	}
+
+	if err := c.Init(clientOptions(ctx)); err != nil {
+		return err
+	}
+
+	fromChart, err := c.helmExecer.LoadRancherChart(ctx.String("from"))
+	if err != nil {
+		return reportOtherRepos(ctx, ctx.String("from"), err)
+	}
+	toChart, err := c.helmExecer.LoadRancherChart(ctx.String("to"))
+	if err != nil {
+		return reportOtherRepos(ctx, ctx.String("to"), err)
+	}
+
+	fromDefaults, err := chartutil.CoalesceValues(fromChart, nil)
+	if err != nil {
+		return err
+	}
+	toDefaults, err := chartutil.CoalesceValues(toChart, nil)
+	if err != nil {
+		return err
+	}
+
+	comparison := chartComparison{
+		From:     fromChart.Metadata.Version,
+		To:       toChart.Metadata.Version,
+		Metadata: diff.Values(comparedMetadata(fromChart.Metadata), comparedMetadata(toChart.Metadata)),
+		Values:   diff.Values(fromDefaults, toDefaults),
+	}
+
+	if output == "json" {
+		data, err := json.MarshalIndent(comparison, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(comparison.Metadata) == 0 {
+		fmt.Println(i18n.T(i18n.MetadataUnchanged, comparison.From, comparison.To))
+	} else {
+		fmt.Println(i18n.T(i18n.MetadataChanged, comparison.From, comparison.To))
+		displayChanges(comparison.Metadata, "  ")
+	}
+	fmt.Println()
+	if len(comparison.Values) == 0 {
+		fmt.Println(i18n.T(i18n.DefaultValuesUnchanged, comparison.From, comparison.To))
+	} else {
+		fmt.Println(i18n.T(i18n.DefaultValuesChanged, comparison.From, comparison.To))
+		displayChanges(comparison.Values, "  ")
+	}
+	return nil
+}
+
+// comparedMetadata is the part of Chart.yaml that matters when planning an upgrade, shaped so it can be diffed like
+// values. Dependencies are keyed by name.
+func comparedMetadata(metadata *chart.Metadata) map[string]interface{} {
+	dependencies := make(map[string]interface{}, len(metadata.Dependencies))
+	for _, dependency := range metadata.Dependencies {
+		dependencies[dependency.Name] = map[string]interface{}{
+			"version":    dependency.Version,
+			"repository": dependency.Repository,
+		}
+	}
+	return map[string]interface{}{
+		"appVersion":   metadata.AppVersion,
+		"kubeVersion":  metadata.KubeVersion,
+		"dependencies": dependencies,
+	}
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/urfave/cli/v2"
+)
+
+type DebugActionClient struct {
+	notes releaseNotesSource
+}
+
+// DebugCommand groups the hidden commands for diagnosing the upgrader itself, they are not part of the supported
+// interface.
+func DebugCommand() *cli.Command {
+	parseNotesFlags := append([]cli.Flag{
+		&cli.StringFlag{
+			Name:     "from",
+			Usage:    "Rancher version the range starts at, its notes only serve the deduplication of the next release",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "to",
+			Usage:    "Rancher version the range ends at",
+			Required: true,
+		},
+	}, noteHeaderFlags()...)
+
+	c := &DebugActionClient{}
+	return &cli.Command{
+		Name:   "debug",
+		Usage:  "Commands for diagnosing the upgrader",
+		Hidden: true,
+		Subcommands: []*cli.Command{
+			{
+				Name:   "parse-notes",
+				Usage:  "Print what the release notes parser extracts from each release of a range: the headers it found, the raw sections and their bullets before and after deduplication",
+				Action: c.ParseNotes,
+				Flags:  parseNotesFlags,
+			},
+		},
+	}
+}
+
+// ParseNotes walks the same steps as parseReleaseNotes and prints the intermediate results of each.
+func (d *DebugActionClient) ParseNotes(ctx *cli.Context) error {
+	from, err := semver.ParseTolerant(ctx.String("from"))
+	if err != nil {
+		return fmt.Errorf("invalid --from version [%s]: %w", ctx.String("from"), err)
+	}
+	to, err := semver.ParseTolerant(ctx.String("to"))
+	if err != nil {
+		return fmt.Errorf("invalid --to version [%s]: %w", ctx.String("to"), err)
+	}
+	if !to.GT(from) {
+		return fmt.Errorf("--to version [%s] must be newer than the --from version [%s]", to, from)
+	}
+	releases, err := getReleasesBetweenInclusive(from.String(), to.String())
+	if err != nil {
+		return err
+	}
+	if d.notes == nil {
+		d.notes = githubReleaseNotes{}
+	}
+
+	sections := configuredNoteSections(ctx)
+	githubReleases, fetchErrs := fetchAllReleaseNotes(d.notes, releases, defaultConcurrency)
+	lastReleaseSections := make(map[string]string, len(sections))
+	for index, release := range releases {
+		fmt.Printf("=== %s\n", release)
+		if fetchErrs[index] != nil {
+			fmt.Printf("fetch failed: %v\n\n", fetchErrs[index])
+			continue
+		}
+		githubRelease := githubReleases[index]
+		fmt.Printf("url: %s\npublished: %s, draft: %t, prerelease: %t\n", githubRelease.HTMLURL, formatPublishedAt(githubRelease.PublishedAt),
+			githubRelease.Draft, githubRelease.Prerelease)
+
+		body := markdownCommentsReg.ReplaceAllString(githubRelease.Body, "")
+		for _, section := range sections {
+			fmt.Printf("\n--- %s\n", section.name)
+			fmt.Printf("header %q: %s\nend header %q: %s\n", section.header, headerState(body, section.header), section.endHeader, headerState(body, section.endHeader))
+
+			fullSectionBody, err := parseNotesSections(section.header, section.endHeader, body)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("raw section (%d bytes): %q\n", len(fullSectionBody), fullSectionBody)
+			printBullets("bullets", parseBulletPoints(fullSectionBody))
+
+			recentAddition := fullSectionBody
+			if lastReleaseSections[section.name] != "" {
+				recentAddition = strings.Replace(fullSectionBody, lastReleaseSections[section.name], "", 1)
+			}
+			lastReleaseSections[section.name] = fullSectionBody
+			if index == 0 {
+				// the notes of the starting release are only compared against
+				continue
+			}
+			printBullets("new since the previous release", parseBulletPoints(recentAddition))
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// headerState reports whether header is found in body and at which byte offset.
+func headerState(body, header string) string {
+	if offset := strings.Index(body, header); offset != -1 {
+		return fmt.Sprintf("found at byte %d", offset)
+	}
+	return "missing"
+}
+
+// printBullets lists bullets quoted, so stray whitespace and leftover markup show.
+func printBullets(title string, bullets []string) {
+	fmt.Printf("%s (%d):\n", title, len(bullets))
+	for _, bullet := range bullets {
+		fmt.Printf("  %q\n", bullet)
+	}
+}
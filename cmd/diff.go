@@ -0,0 +1,336 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/enescakir/emoji"
+	"github.com/fatih/color"
+	"github.com/ghodss/yaml"
+	"github.com/rmweir/rancher-upgrader/internal/diff"
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
+	"github.com/urfave/cli/v2"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+const (
+	diffFormatFields  = "fields"
+	diffFormatUnified = "unified"
+	diffFormatJSON    = "json"
+)
+
+// diffOptions is how the manifest and default values diffs are shown. Context is the number of unchanged lines around
+// each change of a unified diff.
+type diffOptions struct {
+	format  string
+	context int
+}
+
+func newDiffOptions(ctx *cli.Context) (diffOptions, error) {
+	opts := diffOptions{format: ctx.String("diff-format"), context: ctx.Int("diff-context")}
+	switch opts.format {
+	case diffFormatFields, diffFormatUnified, diffFormatJSON:
+	default:
+		return diffOptions{}, fmt.Errorf("unsupported diff format [%s], must be one of: %s, %s, %s", opts.format, diffFormatFields, diffFormatUnified, diffFormatJSON)
+	}
+	if opts.context < 0 {
+		return diffOptions{}, fmt.Errorf("--diff-context must not be negative")
+	}
+	return opts, nil
+}
+
+func displayManifestDiff(oldManifest, newManifest string, opts diffOptions) error {
+	resourceChanges, err := diff.Manifests(oldManifest, newManifest)
+	if err != nil {
+		return fmt.Errorf("failed to diff rendered manifests: %w", err)
+	}
+
+	if opts.format == diffFormatJSON {
+		masked := make([]diff.ResourceChange, len(resourceChanges))
+		for i, resourceChange := range resourceChanges {
+			resourceChange.Changes = maskSecretChanges(resourceChange)
+			masked[i] = resourceChange
+		}
+		return printJSON(masked)
+	}
+
+	if len(resourceChanges) == 0 {
+		fmt.Println(i18n.T(i18n.NoResourceChanges))
+		return nil
+	}
+
+	fmt.Println(i18n.T(i18n.ResourceChangesHeader))
+	for _, resourceChange := range resourceChanges {
+		switch resourceChange.Type {
+		case diff.Added:
+			color.Green("+ %s", resourceChange.Resource)
+		case diff.Removed:
+			color.Red("- %s", resourceChange.Resource)
+		case diff.Modified:
+			color.Yellow("~ %s", resourceChange.Resource)
+		}
+		if opts.format == diffFormatUnified {
+			if err := displayResourceUnified(resourceChange, opts.context); err != nil {
+				return err
+			}
+		} else if resourceChange.Type == diff.Modified {
+			displayChanges(maskSecretChanges(resourceChange), "    ")
+		}
+	}
+	fmt.Println()
+	displayConfigChanges(resourceChanges)
+	return nil
+}
+
+// displayResourceUnified shows the resource's documents as a unified diff, with Secret data masked.
+func displayResourceUnified(resourceChange diff.ResourceChange, context int) error {
+	var documents [2]string
+	for i, doc := range []map[string]interface{}{resourceChange.Old, resourceChange.New} {
+		if doc == nil {
+			continue
+		}
+		data, err := yaml.Marshal(maskSecretDocument(resourceChange.Resource, doc))
+		if err != nil {
+			return err
+		}
+		documents[i] = string(data)
+	}
+	displayUnified(diff.Unified("installed/"+resourceChange.Resource.String(), "upgraded/"+resourceChange.Resource.String(),
+		documents[0], documents[1], context), "    ")
+	return nil
+}
+
+// maskSecretDocument returns a copy of a Secret document with every data value masked, other documents are returned
+// as they are.
+func maskSecretDocument(resource diff.Resource, doc map[string]interface{}) map[string]interface{} {
+	if resource.Kind != "Secret" {
+		return doc
+	}
+	masked := make(map[string]interface{}, len(doc))
+	for key, value := range doc {
+		masked[key] = value
+	}
+	for _, field := range []string{"data", "stringData"} {
+		data, ok := doc[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		maskedData := make(map[string]interface{}, len(data))
+		for key := range data {
+			maskedData[key] = redactedValue
+		}
+		masked[field] = maskedData
+	}
+	return masked
+}
+
+func displayUnified(unified, indent string) {
+	for _, line := range strings.Split(strings.TrimSuffix(unified, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++") || strings.HasPrefix(line, "---"):
+			fmt.Printf("%s%s\n", indent, line)
+		case strings.HasPrefix(line, "+"):
+			color.Green("%s%s", indent, line)
+		case strings.HasPrefix(line, "-"):
+			color.Red("%s%s", indent, line)
+		case strings.HasPrefix(line, "@@"):
+			color.Cyan("%s%s", indent, line)
+		default:
+			fmt.Printf("%s%s\n", indent, line)
+		}
+	}
+}
+
+func printJSON(value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// displayConfigChanges repeats the ConfigMap and Secret changes on their own, as changed configuration and credentials
+// are what an upgrade most often breaks. Secrets that would be replaced instead of updated are called out.
+func displayConfigChanges(resourceChanges []diff.ResourceChange) {
+	var configChanges []diff.ResourceChange
+	for _, resourceChange := range resourceChanges {
+		if resourceChange.Resource.Kind == "ConfigMap" || resourceChange.Resource.Kind == "Secret" {
+			configChanges = append(configChanges, resourceChange)
+		}
+	}
+	if len(configChanges) == 0 {
+		fmt.Println(i18n.T(i18n.NoConfigChanges))
+		fmt.Println()
+		return
+	}
+
+	fmt.Println(i18n.T(i18n.ConfigChangesHeader))
+	for _, resourceChange := range configChanges {
+		switch resourceChange.Type {
+		case diff.Added:
+			color.Green("+ %s", resourceChange.Resource)
+		case diff.Removed:
+			color.Red("- %s", resourceChange.Resource)
+		case diff.Modified:
+			color.Yellow("~ %s", resourceChange.Resource)
+			displayChanges(maskSecretChanges(resourceChange), "    ")
+		}
+		if resourceChange.Resource.Kind == "Secret" && secretReplaced(resourceChange) {
+			color.Red("    %s", i18n.T(i18n.SecretReplaced, emoji.Warning))
+		}
+	}
+	fmt.Println()
+}
+
+// maskSecretChanges hides the values of changed Secret data, only that a key was added, removed or changed is shown.
+func maskSecretChanges(resourceChange diff.ResourceChange) []diff.Change {
+	if resourceChange.Resource.Kind != "Secret" {
+		return resourceChange.Changes
+	}
+	masked := make([]diff.Change, len(resourceChange.Changes))
+	for i, change := range resourceChange.Changes {
+		if isSecretDataPath(change.Path) {
+			change.Old = maskSecretData(change.Old)
+			change.New = maskSecretData(change.New)
+		}
+		masked[i] = change
+	}
+	return masked
+}
+
+// isSecretDataPath reports whether path is a Secret's data or stringData, a whole map added or removed, or a key of it.
+func isSecretDataPath(path string) bool {
+	return path == "data" || path == "stringData" || strings.HasPrefix(path, "data.") || strings.HasPrefix(path, "stringData.")
+}
+
+// maskSecretData masks a changed Secret value. The keys of a whole data map are kept, its values masked.
+func maskSecretData(value interface{}) interface{} {
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		masked := make(map[string]interface{}, len(value))
+		for key := range value {
+			masked[key] = redactedValue
+		}
+		return masked
+	default:
+		return redactedValue
+	}
+}
+
+// secretReplaced reports whether the Secret is deleted, or changed in a way kubernetes only allows by recreating it:
+// its type, or its data while it is made immutable.
+func secretReplaced(resourceChange diff.ResourceChange) bool {
+	if resourceChange.Type == diff.Removed {
+		return true
+	}
+	var immutable, dataChanged bool
+	for _, change := range resourceChange.Changes {
+		switch {
+		case change.Path == "type":
+			return true
+		case change.Path == "immutable":
+			immutable = change.New == true
+		case isSecretDataPath(change.Path):
+			dataChanged = true
+		}
+	}
+	return immutable && dataChanged
+}
+
+// displayUpgradeHooks lists the hook jobs and other hook resources helm runs around the upgrade, in the order helm runs
+// them within each event: by weight, then by name.
+func displayUpgradeHooks(hooks []*release.Hook) {
+	type upgradeHook struct {
+		hook   *release.Hook
+		events []string
+	}
+	var upgradeHooks []upgradeHook
+	for _, hook := range hooks {
+		var events []string
+		for _, event := range hook.Events {
+			if event == release.HookPreUpgrade || event == release.HookPostUpgrade {
+				events = append(events, event.String())
+			}
+		}
+		if len(events) != 0 {
+			upgradeHooks = append(upgradeHooks, upgradeHook{hook: hook, events: events})
+		}
+	}
+
+	if len(upgradeHooks) == 0 {
+		fmt.Println(i18n.T(i18n.NoUpgradeHooks))
+		return
+	}
+
+	sort.SliceStable(upgradeHooks, func(i, j int) bool {
+		if upgradeHooks[i].hook.Weight != upgradeHooks[j].hook.Weight {
+			return upgradeHooks[i].hook.Weight < upgradeHooks[j].hook.Weight
+		}
+		return upgradeHooks[i].hook.Name < upgradeHooks[j].hook.Name
+	})
+	fmt.Println(i18n.T(i18n.UpgradeHooksHeader))
+	for _, upgradeHook := range upgradeHooks {
+		fmt.Printf("  %s %s [%s] weight %d\n", upgradeHook.hook.Kind, upgradeHook.hook.Name, strings.Join(upgradeHook.events, ", "), upgradeHook.hook.Weight)
+	}
+	fmt.Println()
+}
+
+func displayChanges(changes []diff.Change, indent string) {
+	for _, change := range changes {
+		switch change.Type {
+		case diff.Added:
+			color.Green("%s+ %s: %v", indent, change.Path, change.New)
+		case diff.Removed:
+			color.Red("%s- %s: %v", indent, change.Path, change.Old)
+		case diff.Modified:
+			color.Yellow("%s~ %s: %v -> %v", indent, change.Path, change.Old, change.New)
+		}
+	}
+}
+
+// displayDefaultValuesDiff shows how the chart's own default values, including subchart defaults, changed between the
+// installed chart and the target chart, and returns the changes.
+func displayDefaultValuesDiff(installedChart, targetChart *chart.Chart, opts diffOptions) ([]diff.Change, error) {
+	installedDefaults, err := chartutil.CoalesceValues(installedChart, nil)
+	if err != nil {
+		return nil, err
+	}
+	targetDefaults, err := chartutil.CoalesceValues(targetChart, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := diff.Values(installedDefaults, targetDefaults)
+	if opts.format == diffFormatJSON {
+		return changes, printJSON(changes)
+	}
+	if len(changes) == 0 {
+		fmt.Println(i18n.T(i18n.DefaultValuesUnchanged, installedChart.Metadata.Version, targetChart.Metadata.Version))
+		return changes, nil
+	}
+
+	fmt.Println(i18n.T(i18n.DefaultValuesChanged, installedChart.Metadata.Version, targetChart.Metadata.Version))
+	if opts.format == diffFormatUnified {
+		installedYAML, err := yaml.Marshal(installedDefaults.AsMap())
+		if err != nil {
+			return nil, err
+		}
+		targetYAML, err := yaml.Marshal(targetDefaults.AsMap())
+		if err != nil {
+			return nil, err
+		}
+		displayUnified(diff.Unified(installedChart.Metadata.Version+"/values.yaml", targetChart.Metadata.Version+"/values.yaml",
+			string(installedYAML), string(targetYAML), opts.context), "  ")
+	} else {
+		displayChanges(changes, "  ")
+	}
+	fmt.Println(i18n.T(i18n.DefaultValuesNote))
+	return changes, nil
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rmweir/rancher-upgrader/internal/diff"
+)
+
+func TestMaskSecretChanges(t *testing.T) {
+	withoutData := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: bootstrap\n  namespace: cattle-system\n"
+	withData := withoutData + "data:\n  password: c3VwZXJzZWNyZXQ=\n"
+	withStringData := withoutData + "stringData:\n  token: supersecret\n"
+
+	tests := []struct {
+		name        string
+		old, new    string
+		wantPath    string
+		wantMasked  string
+		wantReplace bool
+	}{
+		{name: "data added", old: withoutData, new: withData, wantPath: "data", wantMasked: "map[password:***]"},
+		{name: "data removed", old: withData, new: withoutData, wantPath: "data", wantMasked: "map[password:***]"},
+		{name: "stringData added", old: withoutData, new: withStringData, wantPath: "stringData", wantMasked: "map[token:***]"},
+		{name: "stringData removed", old: withStringData, new: withoutData, wantPath: "stringData", wantMasked: "map[token:***]"},
+		{
+			name:        "data added while made immutable",
+			old:         withoutData,
+			new:         withData + "immutable: true\n",
+			wantPath:    "data",
+			wantMasked:  "map[password:***]",
+			wantReplace: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resourceChanges, err := diff.Manifests(tt.old, tt.new)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resourceChanges) != 1 {
+				t.Fatalf("diff.Manifests() = %d resource changes, want 1", len(resourceChanges))
+			}
+			var found bool
+			for _, change := range maskSecretChanges(resourceChanges[0]) {
+				shown := fmt.Sprint(change.Old, change.New)
+				if strings.Contains(shown, "c3VwZXJzZWNyZXQ=") || strings.Contains(shown, "supersecret") {
+					t.Errorf("change of [%s] shows the secret value: %s", change.Path, shown)
+				}
+				if change.Path != tt.wantPath {
+					continue
+				}
+				found = true
+				masked := change.New
+				if masked == nil {
+					masked = change.Old
+				}
+				if got := fmt.Sprint(masked); got != tt.wantMasked {
+					t.Errorf("change of [%s] = %s, want %s", change.Path, got, tt.wantMasked)
+				}
+			}
+			if !found {
+				t.Errorf("no change of [%s] found in %v", tt.wantPath, resourceChanges[0].Changes)
+			}
+			if got := secretReplaced(resourceChanges[0]); got != tt.wantReplace {
+				t.Errorf("secretReplaced() = %t, want %t", got, tt.wantReplace)
+			}
+		})
+	}
+}
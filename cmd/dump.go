@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+
+	"github.com/rmweir/rancher-upgrader/internal/diff"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// releaseDump is the release state written by --dump-release-json for bug reports. The chart templates and files are
+// left out, the chart metadata identifies them.
+type releaseDump struct {
+	RunID     string                   `json:"runId"`
+	Name      string                   `json:"name"`
+	Namespace string                   `json:"namespace"`
+	Revision  int                      `json:"revision"`
+	Info      *release.Info            `json:"info,omitempty"`
+	Chart     *chart.Metadata          `json:"chart,omitempty"`
+	Config    map[string]interface{}   `json:"config,omitempty"`
+	Manifest  []map[string]interface{} `json:"manifest,omitempty"`
+}
+
+// dumpRelease writes targetRelease as JSON to path, along with the ID of the run that dumped it. Config keys matching
+// redactKeys are redacted and Secret data in the manifest is masked, anything else in the config is written as it is.
+// The file is only readable by the user.
+func dumpRelease(path, runID string, targetRelease *release.Release, redactKeys []string) error {
+	resources, err := diff.ParseManifest(targetRelease.Manifest)
+	if err != nil {
+		return err
+	}
+	sorted := make([]diff.Resource, 0, len(resources))
+	for resource := range resources {
+		sorted = append(sorted, resource)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].String() < sorted[j].String() })
+
+	dump := releaseDump{
+		RunID:     runID,
+		Name:      targetRelease.Name,
+		Namespace: targetRelease.Namespace,
+		Revision:  targetRelease.Version,
+		Info:      targetRelease.Info,
+		Config:    redactValues(targetRelease.Config, redactKeys),
+	}
+	if targetRelease.Chart != nil {
+		dump.Chart = targetRelease.Chart.Metadata
+	}
+	for _, resource := range sorted {
+		dump.Manifest = append(dump.Manifest, maskSecretDocument(resource, resources[resource]))
+	}
+
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/rmweir/rancher-upgrader/internal/clock"
+)
+
+// Event names of the --events-stream schema. Names and fields are only ever added to, so wrapping tools can rely on
+// them.
+const (
+	eventDetectedRelease        = "detected-release"
+	eventComputedTarget         = "computed-target"
+	eventFetchingNotes          = "fetching-notes"
+	eventReleaseReviewed        = "release-reviewed"
+	eventKnownIssueAcknowledged = "known-issue-acknowledged"
+	eventUpgradeStarted         = "upgrade-started"
+	eventUpgradeCompleted       = "upgrade-completed"
+)
+
+// event is a single line of the --events-stream output.
+type event struct {
+	RunID       string    `json:"runId"`
+	Event       string    `json:"event"`
+	Time        time.Time `json:"time"`
+	Release     string    `json:"release,omitempty"`
+	Namespace   string    `json:"namespace,omitempty"`
+	Version     string    `json:"version,omitempty"`
+	FromVersion string    `json:"fromVersion,omitempty"`
+	ToVersion   string    `json:"toVersion,omitempty"`
+	Issue       string    `json:"issue,omitempty"`
+	DryRun      *bool     `json:"dryRun,omitempty"`
+	Revision    int       `json:"revision,omitempty"`
+}
+
+// eventStream writes events as JSON lines. A nil eventStream drops every event, so callers never need to check
+// whether --events-stream is set.
+type eventStream struct {
+	mu    sync.Mutex
+	out   io.Writer
+	runID string
+	clock clock.Clock
+}
+
+// openEventStream starts the stream on stdout for - and on the file or named pipe at path otherwise. When the stream
+// takes stdout, the human readable output moves to stderr. The returned function closes the stream.
+func openEventStream(path, runID string, clk clock.Clock) (*eventStream, func() error, error) {
+	if path == "-" {
+		stream := &eventStream{out: os.Stdout, runID: runID, clock: clk}
+		redirectOutputToStderr()
+		return stream, func() error { return nil }, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &eventStream{out: file, runID: runID, clock: clk}, file.Close, nil
+}
+
+func (s *eventStream) emit(e event) {
+	if s == nil {
+		return
+	}
+	e.RunID = s.runID
+	e.Time = s.clock.Now().UTC()
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.out.Write(append(line, '\n'))
+}
+
+// redirectOutputToStderr sends everything printed for the user to stderr so stdout only carries events.
+func redirectOutputToStderr() {
+	os.Stdout = os.Stderr
+	color.Output = color.Error
+}
+
+func boolPointer(value bool) *bool {
+	return &value
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
+)
+
+// schemaProperty is the part of a values.schema.json property the guided values editor understands.
+type schemaProperty struct {
+	// Type is a type name or a list of them, e.g. ["string", "null"]
+	Type        interface{}               `json:"type"`
+	Description string                    `json:"description"`
+	Default     interface{}               `json:"default"`
+	Enum        []interface{}             `json:"enum"`
+	Properties  map[string]schemaProperty `json:"properties"`
+}
+
+// types returns the types p allows, null left out since an empty answer keeps the value instead.
+func (p schemaProperty) types() []string {
+	var types []string
+	switch typed := p.Type.(type) {
+	case string:
+		types = append(types, typed)
+	case []interface{}:
+		for _, t := range typed {
+			if name, ok := t.(string); ok {
+				types = append(types, name)
+			}
+		}
+	}
+	filtered := types[:0]
+	for _, t := range types {
+		if t != "null" {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
+}
+
+// guidedValuesPrompt walks the user through the properties of the chart's values.schema.json, prompting for each
+// scalar property with its description and its current or default value. Objects with properties are walked into,
+// other objects and arrays are left to a values file. Every answer is checked against the property's types and enum.
+// The returned values are a copy of values with the answers applied.
+func guidedValuesPrompt(schemaJSON []byte, values map[string]interface{}, redactKeys []string, reader *prompter) (map[string]interface{}, error) {
+	var schema schemaProperty
+	if err := json.Unmarshal(schemaJSON, &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse the chart's values.schema.json: %w", err)
+	}
+	fmt.Println(i18n.T(i18n.GuidedValuesIntro))
+	return guidedProperties("", schema.Properties, values, redactKeys, reader)
+}
+
+func guidedProperties(path string, properties map[string]schemaProperty, values map[string]interface{}, redactKeys []string, reader *prompter) (map[string]interface{}, error) {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property := properties[name]
+		keyPath := name
+		if path != "" {
+			keyPath = path + "." + name
+		}
+		current, isSet := values[name]
+
+		if len(property.Properties) != 0 {
+			nested, _ := current.(map[string]interface{})
+			updated, err := guidedProperties(keyPath, property.Properties, nested, redactKeys, reader)
+			if err != nil {
+				return nil, err
+			}
+			if len(updated) != 0 {
+				values = withValue(values, name, updated)
+			}
+			continue
+		}
+
+		types := property.types()
+		if !isScalarProperty(types) {
+			fmt.Println(i18n.T(i18n.GuidedValueNeedsFile, keyPath, strings.Join(types, "|")))
+			continue
+		}
+		if !isSet {
+			current = property.Default
+		}
+		shown := ""
+		if current != nil {
+			shown = fmt.Sprint(current)
+			if matchesRedactKey(name, keyPath, redactKeys) {
+				shown = redactedValue
+			}
+		}
+
+		hint := strings.Join(types, "|")
+		if len(property.Enum) != 0 {
+			options := make([]string, 0, len(property.Enum))
+			for _, option := range property.Enum {
+				options = append(options, fmt.Sprint(option))
+			}
+			hint = strings.Join(options, "|")
+		}
+		if property.Description != "" {
+			fmt.Printf("\n%s\n", property.Description)
+		} else {
+			fmt.Println()
+		}
+		for {
+			fmt.Print(i18n.T(i18n.GuidedValuePrompt, keyPath, hint, shown))
+			answer, err := reader.ReadString('\n')
+			if err != nil {
+				return nil, err
+			}
+			if answer = strings.TrimSpace(answer); answer == "" {
+				break
+			}
+			value, err := parseSchemaValue(answer, types, property.Enum)
+			if err != nil {
+				color.Red("%s", i18n.T(i18n.GuidedValueInvalid, keyPath, err))
+				continue
+			}
+			values = withValue(values, name, value)
+			break
+		}
+	}
+	return values, nil
+}
+
+// isScalarProperty reports whether a property of types can be entered on a single line. A property without a type
+// is taken as a string.
+func isScalarProperty(types []string) bool {
+	for _, t := range types {
+		switch t {
+		case "string", "integer", "number", "boolean":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// parseSchemaValue converts answer to the first of types it is valid for, trying strings last so "true" and "3" keep
+// their type when the property allows it. The value must be one of enum when the property has one.
+func parseSchemaValue(answer string, types []string, enum []interface{}) (interface{}, error) {
+	if len(types) == 0 {
+		types = []string{"string"}
+	}
+	ordered := append([]string(nil), types...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[j] == "string" && ordered[i] != "string" })
+
+	var value interface{}
+	for _, t := range ordered {
+		var err error
+		switch t {
+		case "integer":
+			value, err = strconv.ParseInt(answer, 10, 64)
+		case "number":
+			value, err = strconv.ParseFloat(answer, 64)
+		case "boolean":
+			value, err = strconv.ParseBool(answer)
+		case "string":
+			value = answer
+		}
+		if err == nil {
+			break
+		}
+		value = nil
+	}
+	if value == nil {
+		return nil, fmt.Errorf("[%s] is not a valid %s", answer, strings.Join(types, " or "))
+	}
+
+	if len(enum) == 0 {
+		return value, nil
+	}
+	allowed := make([]string, 0, len(enum))
+	for _, option := range enum {
+		// JSON numbers decode as float64, compare them by their text
+		if fmt.Sprint(option) == fmt.Sprint(value) {
+			return value, nil
+		}
+		allowed = append(allowed, fmt.Sprint(option))
+	}
+	return nil, fmt.Errorf("[%s] must be one of: %s", answer, strings.Join(allowed, ", "))
+}
+
+// withValue returns a copy of values with key set to value, values itself is left untouched.
+func withValue(values map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	updated := make(map[string]interface{}, len(values)+1)
+	for k, v := range values {
+		updated[k] = v
+	}
+	updated[key] = value
+	return updated
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rmweir/rancher-upgrader/internal/helm"
+	"github.com/urfave/cli/v2"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+type HistoryActionClient struct {
+	helmExecer helmExecer
+}
+
+func HistoryCommand() *cli.Command {
+	flags := append(clusterFlags(), &cli.StringFlag{
+		Name:  "output",
+		Usage: "Output format, one of: text, json",
+		Value: "text",
+	})
+
+	c := &HistoryActionClient{}
+	return &cli.Command{
+		Name:   "history",
+		Usage:  "Show the revisions of the installed rancher release",
+		Action: c.History,
+		Flags:  flags,
+	}
+}
+
+// historyRevision is a revision of the history command's JSON output.
+type historyRevision struct {
+	Revision     int    `json:"revision"`
+	Updated      string `json:"updated,omitempty"`
+	Status       string `json:"status"`
+	ChartVersion string `json:"chartVersion"`
+	AppVersion   string `json:"appVersion,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+func (h *HistoryActionClient) History(ctx *cli.Context) error {
+	output := ctx.String("output")
+	if output != "text" && output != "json" {
+		return fmt.Errorf("unsupported output format [%s], must be one of: text, json", output)
+	}
+
+	// prompts go to stderr so the history can be piped
+	targetRelease, client, cleanup, err := selectScopedRancherRelease(ctx, newPrompter(os.Stdin, false), os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if h.helmExecer == nil {
+		h.helmExecer = client
+	}
+
+	revisions, err := h.helmExecer.History(targetRelease.Namespace, targetRelease.Name)
+	if err != nil {
+		return err
+	}
+	history := make([]historyRevision, 0, len(revisions))
+	for _, revision := range revisions {
+		entry := historyRevision{Revision: revision.Version}
+		if revision.Chart != nil && revision.Chart.Metadata != nil {
+			entry.ChartVersion = revision.Chart.Metadata.Version
+			entry.AppVersion = revision.Chart.Metadata.AppVersion
+		}
+		if revision.Info != nil {
+			entry.Status = revision.Info.Status.String()
+			entry.Description = revision.Info.Description
+			if !revision.Info.LastDeployed.IsZero() {
+				entry.Updated = revision.Info.LastDeployed.UTC().Format(time.RFC3339)
+			}
+		}
+		history = append(history, entry)
+	}
+
+	if output == "json" {
+		data, err := json.MarshalIndent(history, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "REVISION\tUPDATED\tSTATUS\tCHART VERSION\tAPP VERSION\tDESCRIPTION")
+	for _, entry := range history {
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%s\t%s\n", entry.Revision, entry.Updated, entry.Status, entry.ChartVersion, entry.AppVersion, entry.Description)
+	}
+	return writer.Flush()
+}
+
+// selectScopedRancherRelease finds the rancher release the cluster flags select and returns it along with a client
+// scoped to its namespace, so revisions are only ever read from and rolled back in that exact release even when
+// releases of the same name exist in other namespaces. The returned cleanup removes a temporary kubeconfig.
+func selectScopedRancherRelease(ctx *cli.Context, reader *prompter, out io.Writer) (*release.Release, helm.Client, func(), error) {
+	kubeconfigPath, cleanup, err := resolveKubeconfig(ctx, os.Stdin)
+	if err != nil {
+		return nil, helm.Client{}, nil, err
+	}
+	opts := clientOptions(ctx)
+	opts.KubeconfigPath = kubeconfigPath
+	client, err := helm.NewReleaseClient(opts)
+	if err != nil {
+		cleanup()
+		return nil, helm.Client{}, nil, err
+	}
+
+	selected, err := findRancherRelease(ctx, client, isInteractive(), reader, out)
+	if err != nil {
+		cleanup()
+		return nil, helm.Client{}, nil, err
+	}
+
+	targetRelease := selected.Release
+	if opts.Namespace != targetRelease.Namespace {
+		opts.Namespace = targetRelease.Namespace
+		if client, err = helm.NewReleaseClient(opts); err != nil {
+			cleanup()
+			return nil, helm.Client{}, nil, err
+		}
+	}
+	return targetRelease, client, cleanup, nil
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/enescakir/emoji"
+	"github.com/rmweir/rancher-upgrader/internal/helm"
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	knownIssuesFormatText = "text"
+	knownIssuesFormatJSON = "json"
+)
+
+// aggregatedKnownIssue is a known issue of the upgraded range along with every release listing it.
+type aggregatedKnownIssue struct {
+	Issue     string   `json:"issue"`
+	Reference string   `json:"reference,omitempty"`
+	Releases  []string `json:"releases"`
+}
+
+// knownIssuesList is the JSON output of --list-known-issues-only.
+type knownIssuesList struct {
+	From        string                 `json:"from"`
+	To          string                 `json:"to"`
+	Unfetched   []string               `json:"unfetched,omitempty"`
+	KnownIssues []aggregatedKnownIssue `json:"knownIssues"`
+}
+
+// listKnownIssues prints the deduplicated known issues of every release after from up to to and nothing else. The
+// chart repository is only consulted when to is more than one minor version past from, to find the hops in between.
+func (u *UpgradeActionClient) listKnownIssues(ctx *cli.Context, from, to string, out io.Writer) error {
+	format := ctx.String("known-issues-format")
+	if format != knownIssuesFormatText && format != knownIssuesFormatJSON {
+		return fmt.Errorf("unsupported known issues format [%s], must be one of: %s, %s", format, knownIssuesFormatText, knownIssuesFormatJSON)
+	}
+
+	current, err := semver.Parse(from)
+	if err != nil {
+		return fmt.Errorf("invalid --from version [%s]: %w", from, err)
+	}
+	target, err := semver.Parse(to)
+	if err != nil {
+		return fmt.Errorf("invalid --to version [%s]: %w", to, err)
+	}
+	if !target.GT(current) {
+		return fmt.Errorf("--to version [%s] must be newer than the --from version [%s]", to, from)
+	}
+
+	upgradePath := []string{target.String()}
+	if !directlyUpgradable(current, target) {
+		if u.helmExecer == nil {
+			if u.helmExecer, err = helm.NewChartClient(clientOptions(ctx)); err != nil {
+				return err
+			}
+		}
+		latestPath, err := u.helmExecer.GetUpgradePath(current.String())
+		if err != nil {
+			return err
+		}
+		if upgradePath, err = supportedPathTo(current, target, latestPath); err != nil {
+			return err
+		}
+	}
+
+	releases := []string{current.String()}
+	hop := current.String()
+	for _, version := range upgradePath {
+		hopReleases, err := getReleasesBetweenInclusive(hop, version)
+		if err != nil {
+			return err
+		}
+		releases = append(releases, hopReleases[1:]...)
+		hop = version
+	}
+
+	var knownIssues []noteSection
+	for _, section := range configuredNoteSections(ctx) {
+		if section.name == sectionKnownIssues {
+			knownIssues = append(knownIssues, section)
+		}
+	}
+	fmt.Println(i18n.T(i18n.ListingKnownIssues, emoji.MagnifyingGlassTiltedLeft, len(releases)-1, from, to))
+	notes, err := parseReleaseNotes(u.notes, releases, knownIssues, ctx.Int("concurrency"))
+	if err != nil {
+		return err
+	}
+
+	list := knownIssuesList{From: from, To: to, KnownIssues: aggregateKnownIssues(notes[1:])}
+	for _, note := range unfetchedNotes(notes[1:]) {
+		list.Unfetched = append(list.Unfetched, note.version)
+	}
+	u.unfetched = list.Unfetched
+
+	if format == knownIssuesFormatJSON {
+		data, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(data))
+		return nil
+	}
+
+	if len(list.Unfetched) != 0 {
+		displayUnfetchedNotes(unfetchedNotes(notes[1:]))
+	}
+	if len(list.KnownIssues) == 0 {
+		fmt.Fprintln(out, i18n.T(i18n.NoKnownIssuesInRange, from, to))
+		return nil
+	}
+	fmt.Fprintln(out, i18n.T(i18n.KnownIssuesInRange, len(list.KnownIssues), from, to))
+	for index, issue := range list.KnownIssues {
+		fmt.Fprintf(out, "%d. [%s] %s\n", index+1, strings.Join(issue.Releases, ", "), issue.Issue)
+	}
+	return nil
+}
+
+// aggregateKnownIssues returns the known issues of notes in the order they first appear. An issue listed by several
+// releases, e.g. because its wording changed and it was no longer recognized as carried forward, is returned once.
+// Issues referring to the same GitHub issue are the same issue.
+func aggregateKnownIssues(notes []releaseNotes) []aggregatedKnownIssue {
+	aggregated := []aggregatedKnownIssue{}
+	seen := map[string]int{}
+	for _, release := range notes {
+		for _, issue := range release.sections[sectionKnownIssues] {
+			issue = strings.TrimSpace(issue)
+			if issue == "" || issue == "-->" {
+				continue
+			}
+			key := issue
+			if reference := issueReference(issue); reference != "" {
+				key = "#" + reference
+			}
+			if index, ok := seen[key]; ok {
+				aggregated[index].Releases = append(aggregated[index].Releases, release.version)
+				continue
+			}
+			seen[key] = len(aggregated)
+			aggregated = append(aggregated, aggregatedKnownIssue{
+				Issue:     issue,
+				Reference: issueReference(issue),
+				Releases:  []string{release.version},
+			})
+		}
+	}
+	return aggregated
+}
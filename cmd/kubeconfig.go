@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rmweir/rancher-upgrader/internal/cleanup"
+	"github.com/urfave/cli/v2"
+)
+
+// stdinKubeconfig is the --kubeconfig value that reads the kubeconfig from stdin.
+const stdinKubeconfig = "-"
+
+// resolveKubeconfig returns the path of the kubeconfig to hand to helm. --kubeconfig-data takes precedence over
+// --kubeconfig. A kubeconfig read from stdin or passed inline is written to a temp file that only the current user can
+// read, removed by the returned cleanup function once helm is done with it or when the run is interrupted.
+func resolveKubeconfig(ctx *cli.Context, stdin io.Reader) (string, func(), error) {
+	path, data := ctx.String("kubeconfig"), ctx.String("kubeconfig-data")
+	noop := func() {}
+
+	var content []byte
+	switch {
+	case data != "":
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return "", noop, fmt.Errorf("--kubeconfig-data must be base64 encoded: %w", err)
+		}
+		content = decoded
+	case path == stdinKubeconfig:
+		read, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", noop, fmt.Errorf("failed to read kubeconfig from stdin: %w", err)
+		}
+		content = read
+	default:
+		return path, noop, nil
+	}
+
+	file, err := os.CreateTemp("", "rancher-upgrader-kubeconfig-*")
+	if err != nil {
+		return "", noop, err
+	}
+	removeFile := cleanup.Register(func() {
+		os.Remove(file.Name())
+	})
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		removeFile()
+		return "", noop, err
+	}
+	if err := file.Close(); err != nil {
+		removeFile()
+		return "", noop, err
+	}
+	return file.Name(), removeFile, nil
+}
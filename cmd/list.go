@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rmweir/rancher-upgrader/internal/helm"
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
+	"github.com/urfave/cli/v2"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// versionCatalog is the part of the helm client the list command needs.
+type versionCatalog interface {
+	rancherReleaseFinder
+	GetNextSupportedRancherChartVersion(currentVersion string) (string, error)
+	ListRancherChartVersions() ([]*repo.ChartVersion, error)
+}
+
+type ListActionClient struct {
+	helmExecer versionCatalog
+}
+
+func ListCommand() *cli.Command {
+	flags := append(clusterFlags(), repoFlags()...)
+	flags = append(flags,
+		&cli.StringFlag{
+			Name:  "current",
+			Usage: "Rancher chart version to mark as current and resolve the next version from, instead of the installed release's. Without either no version is marked",
+		},
+		&cli.BoolFlag{
+			Name:  "stay-on-minor",
+			Usage: "Only resolve a newer patch of the current minor version as the next version",
+		},
+		upgradePolicyFlag(),
+		&cli.StringFlag{
+			Name:  "output",
+			Usage: "Output format, one of: text, json",
+			Value: "text",
+		},
+	)
+
+	c := &ListActionClient{}
+	return &cli.Command{
+		Name:    "list",
+		Aliases: []string{"versions"},
+		Usage:   "List the available rancher chart versions, marking the current and the next supported version",
+		Action:  c.List,
+		Flags:   flags,
+	}
+}
+
+// listedVersion is a rancher chart version of the list command's JSON output.
+type listedVersion struct {
+	Version    string `json:"version"`
+	AppVersion string `json:"appVersion,omitempty"`
+	Created    string `json:"created,omitempty"`
+	Digest     string `json:"digest,omitempty"`
+	Current    bool   `json:"current"`
+	Next       bool   `json:"next"`
+}
+
+// versionList is the JSON output of the list command.
+type versionList struct {
+	Current  string          `json:"current,omitempty"`
+	Next     string          `json:"next,omitempty"`
+	Versions []listedVersion `json:"versions"`
+}
+
+func (l *ListActionClient) List(ctx *cli.Context) error {
+	output := ctx.String("output")
+	if output != "text" && output != "json" {
+		return fmt.Errorf("unsupported output format [%s], must be one of: text, json", output)
+	}
+
+	// the installed release is only looked up when a cluster is configured, listing versions works without one
+	useCluster := ctx.String("current") == "" && (ctx.String("kubeconfig") != "" || ctx.String("kubeconfig-data") != "")
+	if l.helmExecer == nil {
+		opts := clientOptions(ctx)
+		newClient := helm.NewChartClient
+		if useCluster {
+			kubeconfigPath, cleanup, err := resolveKubeconfig(ctx, os.Stdin)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			opts.KubeconfigPath = kubeconfigPath
+			newClient = helm.NewClient
+		}
+		client, err := newClient(opts)
+		if err != nil {
+			return err
+		}
+		l.helmExecer = client
+	}
+
+	list := versionList{Current: ctx.String("current"), Versions: []listedVersion{}}
+	if useCluster {
+		// prompts go to stderr so the list can be piped
+		selected, err := findRancherRelease(ctx, l.helmExecer, isInteractive(), newPrompter(os.Stdin, false), os.Stderr)
+		if err != nil {
+			return err
+		}
+		list.Current = selected.Version
+	}
+	if list.Current != "" {
+		next, err := l.helmExecer.GetNextSupportedRancherChartVersion(list.Current)
+		if err != nil {
+			return err
+		}
+		if next != list.Current {
+			list.Next = next
+		}
+	}
+
+	chartVersions, err := l.helmExecer.ListRancherChartVersions()
+	if err != nil {
+		return err
+	}
+	for _, chartVersion := range chartVersions {
+		listed := listedVersion{
+			Version:    chartVersion.Version,
+			AppVersion: chartVersion.AppVersion,
+			Digest:     chartVersion.Digest,
+			Current:    chartVersion.Version == list.Current,
+			Next:       chartVersion.Version == list.Next,
+		}
+		if !chartVersion.Created.IsZero() {
+			listed.Created = chartVersion.Created.UTC().Format(time.RFC3339)
+		}
+		list.Versions = append(list.Versions, listed)
+	}
+
+	if output == "json" {
+		data, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(list.Versions) == 0 {
+		fmt.Println(i18n.T(i18n.NoChartVersionsAvailable))
+		return nil
+	}
+	for index, chartVersion := range chartVersions {
+		listed := list.Versions[index]
+		line := formatChartVersion(chartVersion.Metadata)
+		if !chartVersion.Created.IsZero() {
+			line += " " + i18n.T(i18n.ChartVersionCreated, chartVersion.Created.UTC().Format("2006-01-02"))
+		}
+		switch {
+		case listed.Current:
+			line += " " + i18n.T(i18n.CurrentVersionMarker)
+		case listed.Next:
+			line += " " + i18n.T(i18n.NextVersionMarker)
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
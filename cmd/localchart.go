@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/blang/semver/v4"
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
+	"github.com/urfave/cli/v2"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// localChartExecer serves the chart loaded from --chart-path in place of the chart repository, which is never
+// consulted.
+type localChartExecer struct {
+	helmExecer
+	chart *chart.Chart
+}
+
+func (l localChartExecer) LoadRancherChart(version string) (*chart.Chart, error) {
+	if version != l.chart.Metadata.Version {
+		return nil, fmt.Errorf("only the chart from --chart-path at version [%s] is available, not [%s]", l.chart.Metadata.Version, version)
+	}
+	return l.chart, nil
+}
+
+// upgradeToLocalChart upgrades targetRelease to the chart from --chart-path. The chart is taken as given, it only has
+// to be the same chart as the installed one and must not be older.
+func (u *UpgradeActionClient) upgradeToLocalChart(ctx *cli.Context, targetRelease *release.Release, localChart *chart.Chart, reader *prompter) error {
+	installed := targetRelease.Chart.Metadata
+	if localChart.Metadata.Name != installed.Name {
+		return fmt.Errorf("the chart at [%s] is [%s], but release [%s] is installed from chart [%s]", ctx.String("chart-path"),
+			localChart.Metadata.Name, targetRelease.Name, installed.Name)
+	}
+
+	current, err := semver.Parse(installed.Version)
+	if err != nil {
+		return err
+	}
+	target, err := semver.Parse(localChart.Metadata.Version)
+	if err != nil {
+		return fmt.Errorf("the chart at [%s] has version [%s], which is not a semantic version: %w", ctx.String("chart-path"), localChart.Metadata.Version, err)
+	}
+	if target.LT(current) {
+		return fmt.Errorf("the chart at [%s] is version [%s], older than the installed chart version [%s], downgrades are not supported",
+			ctx.String("chart-path"), localChart.Metadata.Version, installed.Version)
+	}
+
+	u.events.emit(event{Event: eventComputedTarget, FromVersion: installed.Version, ToVersion: localChart.Metadata.Version})
+	if err := checkSupport(u.support, installed.Version, localChart.Metadata.Version, ctx.Bool("enforce-support"), u.clock.Now()); err != nil {
+		return err
+	}
+
+	fmt.Println(i18n.T(i18n.LocalChartUpdate, ctx.String("chart-path"), formatChartVersion(installed), formatChartVersion(localChart.Metadata)))
+	cont, err := promptForContinue(reader)
+	if err != nil {
+		return err
+	}
+	if !cont {
+		return nil
+	}
+
+	_, err = u.upgradeTo(ctx, targetRelease, localChart.Metadata.Version, reader)
+	return err
+}
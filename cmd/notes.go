@@ -0,0 +1,766 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/enescakir/emoji"
+	"github.com/fatih/color"
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
+	"github.com/rmweir/rancher-upgrader/internal/releasenotes"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	ghReleaseNotesAPIPrefix      = "https://api.github.com/repos/rancher/rancher/releases/tags/"
+	rancherReleaseNotesPrefix    = "https://github.com/rancher/rancher/releases/tag/"
+	rancherCompareURLPrefix      = "https://github.com/rancher/rancher/compare/"
+	majorBugFixHeader            = "# Major Bug Fixes"
+	rancherBehaviorChangesHeader = "# Rancher Behavior Changes"
+	knownIssuesHeader            = "# Known Issues"
+	installUpgradeNotesHeader    = "# Install/Upgrade Notes"
+	versionsHeader               = "# Versions"
+
+	sectionBugfixes        = "bugfixes"
+	sectionBehaviorChanges = "behavior-changes"
+	sectionKnownIssues     = "known-issues"
+	sectionInstallNotes    = "install-notes"
+)
+
+// noteSection is a section of the rancher release notes, spanning from its header up to the next section's header.
+// Its notes are shown in color, labeled by label in the legend.
+type noteSection struct {
+	name      string
+	header    string
+	endHeader string
+	label     i18n.Message
+	color     *color.Color
+}
+
+// noteSections are in the order they appear in the release notes, which is also the order they are displayed in.
+var noteSections = []noteSection{
+	{name: sectionBugfixes, header: majorBugFixHeader, endHeader: rancherBehaviorChangesHeader, label: i18n.SectionBugfixes, color: color.New(color.FgGreen)},
+	{name: sectionBehaviorChanges, header: rancherBehaviorChangesHeader, endHeader: knownIssuesHeader, label: i18n.SectionBehaviorChanges, color: color.New(color.FgYellow)},
+	{name: sectionKnownIssues, header: knownIssuesHeader, endHeader: installUpgradeNotesHeader, label: i18n.SectionKnownIssues, color: color.New(color.FgRed)},
+	{name: sectionInstallNotes, header: installUpgradeNotesHeader, endHeader: versionsHeader, label: i18n.SectionInstallNotes, color: color.New(color.FgCyan)},
+}
+
+// printSectionLine prints a line of the notes of the named section in the section's color.
+func printSectionLine(name, format string, a ...interface{}) {
+	for _, section := range noteSections {
+		if section.name == name {
+			section.color.Printf(format+"\n", a...)
+			return
+		}
+	}
+	fmt.Printf(format+"\n", a...)
+}
+
+// displayLegend lists the colors of sections, unless colors are disabled.
+func displayLegend(sections []noteSection) {
+	if color.NoColor || len(sections) == 0 {
+		return
+	}
+	labels := make([]string, len(sections))
+	for i, section := range sections {
+		labels[i] = section.color.Sprint(i18n.T(section.label))
+	}
+	fmt.Println(i18n.T(i18n.NotesLegend, strings.Join(labels, ", ")))
+}
+
+func sectionNames() []string {
+	names := make([]string, len(noteSections))
+	for i, section := range noteSections {
+		names[i] = section.name
+	}
+	return names
+}
+
+// configuredNoteSections returns noteSections with the headers set by the header flags. Each section ends where the
+// next one starts, so the end headers follow from the same flags.
+func configuredNoteSections(ctx *cli.Context) []noteSection {
+	headers := []string{
+		ctx.String("bugfix-header"),
+		ctx.String("behavior-changes-header"),
+		ctx.String("known-issues-header"),
+		ctx.String("install-notes-header"),
+		ctx.String("versions-header"),
+	}
+	sections := make([]noteSection, len(noteSections))
+	for i, section := range noteSections {
+		section.header, section.endHeader = headers[i], headers[i+1]
+		sections[i] = section
+	}
+	return sections
+}
+
+// selectNoteSections returns the sections of available matching names, in release notes order.
+func selectNoteSections(available []noteSection, names []string) ([]noteSection, error) {
+	selected := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, section := range available {
+			if section.name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown release notes section [%s], must be one of: %s", name, strings.Join(sectionNames(), ", "))
+		}
+		selected[name] = true
+	}
+
+	var sections []noteSection
+	for _, section := range available {
+		if selected[section.name] {
+			sections = append(sections, section)
+		}
+	}
+	return sections, nil
+}
+
+var (
+	markdownCommentsReg = regexp.MustCompile("<!--[A-Za-z0-9-#/, ]*-->")
+	issueReferenceReg   = regexp.MustCompile(`(?:github\.com/[\w.-]+/[\w.-]+/issues/|#)(\d+)`)
+)
+
+func getReleasesBetweenInclusive(startingRelease, finalRelease string) ([]string, error) {
+	startingSemver, err := semver.New(startingRelease)
+	if err != nil {
+		return nil, err
+	}
+	finalSemver, err := semver.New(finalRelease)
+	if err != nil {
+		return nil, err
+	}
+
+	// a minor upgrade goes straight from the starting release to the new minor's first patch
+	releases := []string{startingRelease}
+	firstPatch := startingSemver.Patch + 1
+	if finalSemver.Major != startingSemver.Major || finalSemver.Minor != startingSemver.Minor {
+		firstPatch = 0
+	}
+	for patch := firstPatch; patch <= finalSemver.Patch; patch++ {
+		releases = append(releases, fmt.Sprintf("%d.%d.%d", finalSemver.Major, finalSemver.Minor, patch))
+	}
+	return releases, nil
+}
+
+// releaseNotes holds the notes of a single rancher release, keyed by section name. Sections only contain what is new
+// since the previous release, as rancher carries notes forward across patches. Sections that were not selected are
+// absent. fetchErr is set when the notes could not be fetched, the sections are empty then.
+type releaseNotes struct {
+	version     string
+	url         string
+	fetchErr    error
+	publishedAt time.Time
+	draft       bool
+	prerelease  bool
+	sections    map[string][]string
+}
+
+// defaultConcurrency is how many release notes are fetched at once unless --concurrency says otherwise.
+const defaultConcurrency = 4
+
+// parseReleaseNotes fetches and parses the notes of every release, fetching up to concurrency of them at once. A
+// release whose notes cannot be fetched does not stop the others, its fetchErr is set instead. What it carried
+// forward then shows up in the release after it.
+func parseReleaseNotes(source releaseNotesSource, releases []string, sections []noteSection, concurrency int) ([]releaseNotes, error) {
+	notes := make([]releaseNotes, len(releases))
+	githubReleases, fetchErrs := fetchAllReleaseNotes(source, releases, concurrency)
+
+	// parsed in order, as what each release carried forward is only known once the one before it is parsed
+	lastReleaseSections := make(map[string]string, len(sections))
+	for index, release := range releases {
+		githubRelease, err := githubReleases[index], fetchErrs[index]
+		if err != nil {
+			notes[index] = releaseNotes{
+				version:  release,
+				url:      rancherReleaseNotesPrefix + "v" + release,
+				fetchErr: err,
+				sections: map[string][]string{},
+			}
+			continue
+		}
+		notes[index].version = release
+		// html_url is the page GitHub actually serves, tags are not always formatted the way the prefix assumes
+		notes[index].url = githubRelease.HTMLURL
+		if notes[index].url == "" {
+			notes[index].url = rancherReleaseNotesPrefix + "v" + release
+		}
+		notes[index].publishedAt = githubRelease.PublishedAt
+		notes[index].draft = githubRelease.Draft
+		notes[index].prerelease = githubRelease.Prerelease
+		notes[index].sections = make(map[string][]string, len(sections))
+
+		body := markdownCommentsReg.ReplaceAllString(githubRelease.Body, "")
+
+		for _, section := range sections {
+			fullSectionBody, err := parseNotesSections(section.header, section.endHeader, body)
+			if err != nil {
+				return nil, err
+			}
+			recentAddition := fullSectionBody
+			if lastReleaseSections[section.name] != "" {
+				recentAddition = strings.Replace(fullSectionBody, lastReleaseSections[section.name], "", 1)
+			}
+			lastReleaseSections[section.name] = fullSectionBody
+			notes[index].sections[section.name] = parseBulletPoints(recentAddition)
+		}
+	}
+	return notes, nil
+}
+
+// walkthroughRelevantNotes shows the notes of every release after the installed one and returns whether the user wants
+// to continue along with every known issue they acknowledged. Releases published before since are collapsed to a single
+// line, their notes still count towards the deduplication of later releases. With skipEmpty, releases with nothing
+// left in sections are not stopped at and summarized at the end instead.
+func walkthroughRelevantNotes(notes []releaseNotes, sections []noteSection, since time.Time, breakingKeywords []string, skipEmpty bool, reader *prompter, events *eventStream) (bool, []string, error) {
+	var acknowledged, skipped []string
+	fmt.Println(i18n.T(i18n.ReleaseCount, len(notes)-1, notes[0].version, notes[len(notes)-1].version))
+	fmt.Println(i18n.T(i18n.ReviewChangesIntro))
+	displayLegend(sections)
+	for index, release := range notes {
+		if index == len(notes)-1 {
+			break
+		}
+		next := notes[index+1]
+		if skipEmpty && isEmptyRelease(next, sections, since) {
+			skipped = append(skipped, next.version)
+			events.emit(event{Event: eventReleaseReviewed, Version: next.version})
+			continue
+		}
+		fmt.Printf("%s -> %s (%s)\n", release.version, next.version, formatPublishedAt(next.publishedAt))
+		fmt.Println(i18n.T(i18n.ReleaseLinks, next.url, fmt.Sprintf("%sv%s...v%s", rancherCompareURLPrefix, release.version, next.version)))
+		if next.draft {
+			color.Yellow("%s", i18n.T(i18n.DraftReleaseNotes, emoji.Warning, next.version))
+		} else if next.prerelease {
+			color.Yellow("%s", i18n.T(i18n.PrereleaseNotes, emoji.Warning, next.version))
+		}
+		if next.fetchErr != nil {
+			color.Red("%s", i18n.T(i18n.NotesNotFetched, emoji.CrossMark, next.version, next.url))
+			continue
+		}
+		if !since.IsZero() && !next.publishedAt.IsZero() && next.publishedAt.Before(since) {
+			fmt.Println(i18n.T(i18n.NotesCollapsedBeforeSince, next.version, since.Format(sinceLayout)))
+			continue
+		}
+		for _, section := range sections {
+			var cont bool
+			var err error
+			bullets := next.sections[section.name]
+			switch section.name {
+			case sectionBugfixes:
+				cont, err = displayBugFixes(next.version, next.url, bullets, reader)
+			case sectionBehaviorChanges:
+				cont, err = displayBehaviorChanges(next.version, bullets, breakingKeywords, reader)
+			case sectionKnownIssues:
+				var releaseAcknowledged []string
+				releaseAcknowledged, cont, err = displayKnownIssues(next.version, bullets, reader)
+				acknowledged = append(acknowledged, releaseAcknowledged...)
+				for _, issue := range releaseAcknowledged {
+					events.emit(event{Event: eventKnownIssueAcknowledged, Version: next.version, Issue: issue})
+				}
+			case sectionInstallNotes:
+				cont, err = displayInstallNotes(next.version, bullets, reader)
+			}
+			if err != nil {
+				return false, acknowledged, err
+			}
+			if !cont {
+				return false, acknowledged, nil
+			}
+		}
+		events.emit(event{Event: eventReleaseReviewed, Version: next.version})
+	}
+	if len(skipped) != 0 {
+		fmt.Println(i18n.T(i18n.SkippedEmptyReleases, len(skipped), strings.Join(skipped, ", ")))
+	}
+	return true, acknowledged, nil
+}
+
+// walkthroughFunc walks the user through the notes of every release after the installed one. It returns whether the
+// user wants to continue and the acknowledged known issues.
+type walkthroughFunc func(notes []releaseNotes, sections []noteSection, since time.Time, breakingKeywords []string, skipEmpty bool, reader *prompter, events *eventStream) (bool, []string, error)
+
+// quietWalkthrough runs walkthrough without printing anything, its prompts must all be answered by reader's assumeYes.
+// Only a summary of what was reviewed is printed, errors are returned as they are.
+func quietWalkthrough(walkthrough walkthroughFunc) walkthroughFunc {
+	return func(notes []releaseNotes, sections []noteSection, since time.Time, breakingKeywords []string, skipEmpty bool, reader *prompter, events *eventStream) (bool, []string, error) {
+		restore, err := discardOutput()
+		if err != nil {
+			return false, nil, err
+		}
+		cont, acknowledged, err := walkthrough(notes, sections, since, breakingKeywords, skipEmpty, reader, events)
+		restore()
+		if err == nil {
+			fmt.Println(i18n.T(i18n.QuietNotesSummary, len(notes)-1, notes[0].version, notes[len(notes)-1].version, len(acknowledged)))
+		}
+		return cont, acknowledged, err
+	}
+}
+
+// discardOutput sends everything printed for the user nowhere until the returned function restores the output.
+func discardOutput() (func(), error) {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	stdout, colorOutput := os.Stdout, color.Output
+	os.Stdout, color.Output = devNull, devNull
+	return func() {
+		os.Stdout, color.Output = stdout, colorOutput
+		devNull.Close()
+	}, nil
+}
+
+// isEmptyRelease reports whether the fetched notes of release have nothing left in sections after deduplication.
+// Draft and prerelease notes and notes collapsed by since are never empty, they are shown for their warning.
+func isEmptyRelease(release releaseNotes, sections []noteSection, since time.Time) bool {
+	if release.fetchErr != nil || release.draft || release.prerelease {
+		return false
+	}
+	if !since.IsZero() && !release.publishedAt.IsZero() && release.publishedAt.Before(since) {
+		return false
+	}
+	for _, section := range sections {
+		for _, bullet := range release.sections[section.name] {
+			if bullet = strings.TrimSpace(bullet); bullet != "" && bullet != "-->" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// unfetchedNotes returns the notes that could not be fetched.
+func unfetchedNotes(notes []releaseNotes) []releaseNotes {
+	var unfetched []releaseNotes
+	for _, note := range notes {
+		if note.fetchErr != nil {
+			unfetched = append(unfetched, note)
+		}
+	}
+	return unfetched
+}
+
+// displayUnfetchedNotes summarizes the releases whose notes could not be fetched, so they can be reviewed by hand.
+func displayUnfetchedNotes(unfetched []releaseNotes) {
+	versions := make([]string, len(unfetched))
+	for i, note := range unfetched {
+		versions[i] = note.version
+	}
+	color.Yellow("%s", i18n.T(i18n.UnfetchedNotesSummary, emoji.Warning, strings.Join(versions, ", ")))
+	for _, note := range unfetched {
+		fmt.Printf("  %s: %s (%v)\n", note.version, note.url, note.fetchErr)
+	}
+}
+
+func hasSection(sections []noteSection, name string) bool {
+	for _, section := range sections {
+		if section.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// matchingKnownIssues returns the known issues of the upgraded releases that match pattern, prefixed with their
+// release.
+func matchingKnownIssues(notes []releaseNotes, pattern *regexp.Regexp) []string {
+	var matched []string
+	// the first release is the one installed, its known issues are not part of the upgrade
+	for _, release := range notes[1:] {
+		for _, issue := range release.sections[sectionKnownIssues] {
+			issue = strings.TrimSpace(issue)
+			if issue == "" || issue == "-->" || !pattern.MatchString(issue) {
+				continue
+			}
+			matched = append(matched, fmt.Sprintf("[%s] %s", release.version, issue))
+		}
+	}
+	return matched
+}
+
+// loadAckedBehaviorChanges reads the behavior changes acknowledged in the file at path, normalized by
+// normalizeBehaviorChange. Empty lines and lines starting with # are skipped. No file acknowledges nothing.
+func loadAckedBehaviorChanges(path string) (map[string]bool, error) {
+	acked := map[string]bool{}
+	if path == "" {
+		return acked, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read --acked-behavior-changes: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		acked[normalizeBehaviorChange(line)] = true
+	}
+	return acked, nil
+}
+
+// normalizeBehaviorChange lowercases change, drops a leading bullet marker and collapses its whitespace, so a
+// change copied from the rendered or the markdown notes matches either way.
+func normalizeBehaviorChange(change string) string {
+	change = strings.TrimLeft(strings.TrimSpace(change), "-*+ ")
+	return strings.ToLower(strings.Join(strings.Fields(change), " "))
+}
+
+// unackedBehaviorChanges returns the behavior changes of the upgraded releases missing from acked, prefixed with
+// their release.
+func unackedBehaviorChanges(notes []releaseNotes, acked map[string]bool) []string {
+	var unacked []string
+	// the first release is the one installed, its behavior changes are not part of the upgrade
+	for _, release := range notes[1:] {
+		for _, change := range release.sections[sectionBehaviorChanges] {
+			change = strings.TrimSpace(change)
+			if change == "" || change == "-->" || acked[normalizeBehaviorChange(change)] {
+				continue
+			}
+			unacked = append(unacked, fmt.Sprintf("[%s] %s", release.version, change))
+		}
+	}
+	return unacked
+}
+
+// defaultImpactKeywords are the words in install/upgrade notes that usually mean the upgrade disrupts rancher or the
+// workloads it manages.
+var defaultImpactKeywords = []string{"downtime", "outage", "disruption", "unavailable", "migration", "migrate", "restart", "interrupt"}
+
+// impactNote is an install/upgrade note that mentions one of the impact keywords.
+type impactNote struct {
+	version string
+	note    string
+	keyword string
+}
+
+// estimateImpact finds the install/upgrade notes that mention any of keywords, ignoring case. It is a heuristic, a
+// note only needs to contain a keyword to match.
+func estimateImpact(notes []releaseNotes, keywords []string) []impactNote {
+	var impact []impactNote
+	// the first release is the one installed, its notes are not part of the upgrade
+	for _, release := range notes[1:] {
+		for _, note := range release.sections[sectionInstallNotes] {
+			if keyword := matchingKeyword(note, keywords); keyword != "" {
+				impact = append(impact, impactNote{version: release.version, note: strings.TrimSpace(note), keyword: keyword})
+			}
+		}
+	}
+	return impact
+}
+
+// displayImpact summarizes the notes found by estimateImpact so maintenance can be scheduled accordingly.
+func displayImpact(impact []impactNote) {
+	if len(impact) == 0 {
+		fmt.Println(i18n.T(i18n.NoImpactFound))
+		return
+	}
+	color.Yellow("%s", i18n.T(i18n.ImpactHeader, emoji.Warning, len(impact)))
+	for _, note := range impact {
+		fmt.Printf("[%s] (%s) %s\n", note.version, note.keyword, note.note)
+	}
+}
+
+// sinceLayout is the date format of --since.
+const sinceLayout = "2006-01-02"
+
+// parseSince parses the --since date as the start of that day in the local timezone. An empty value disables the
+// filter.
+func parseSince(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	since, err := time.ParseInLocation(sinceLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since date [%s], must be in YYYY-MM-DD form", value)
+	}
+	return since, nil
+}
+
+// formatPublishedAt shows when a release was published in the user's local timezone.
+func formatPublishedAt(publishedAt time.Time) string {
+	if publishedAt.IsZero() {
+		return i18n.T(i18n.NotPublished)
+	}
+	return i18n.T(i18n.Published, publishedAt.Local().Format("2006-01-02 15:04 MST"))
+}
+
+func displayBugFixes(release, url string, bugfixes []string, reader *prompter) (bool, error) {
+	var displayedOpeningMessage bool
+
+	for _, bugfix := range bugfixes {
+		if bugfix == "" || bugfix == "-->" {
+			continue
+		}
+		if !displayedOpeningMessage {
+			printSectionLine(sectionBugfixes, "%s", i18n.T(i18n.BugfixesHeader, release))
+			displayedOpeningMessage = true
+		}
+		printSectionLine(sectionBugfixes, "%v %s", emoji.CheckMark, bugfix)
+	}
+	if !displayedOpeningMessage {
+		fmt.Println(i18n.T(i18n.NoBugfixes))
+	}
+	fmt.Println(i18n.T(i18n.BugfixesReadMore, release, url))
+	return promptForContinue(reader)
+}
+
+// defaultBreakingKeywords are the phrases in behavior changes that usually mean something that worked before stops
+// working after the upgrade.
+var defaultBreakingKeywords = []string{"removed", "no longer", "breaking", "must", "deprecated"}
+
+// displayBehaviorChanges lists the behavior changes of a release. Changes mentioning one of breakingKeywords are
+// flagged and acknowledged one by one, the others are only listed.
+func displayBehaviorChanges(release string, behaviorChanges []string, breakingKeywords []string, reader *prompter) (bool, error) {
+	var breaking, informational []string
+	for _, change := range behaviorChanges {
+		if change == "" || change == "-->" {
+			continue
+		}
+		if matchingKeyword(change, breakingKeywords) != "" {
+			breaking = append(breaking, change)
+		} else {
+			informational = append(informational, change)
+		}
+	}
+	if len(breaking) == 0 && len(informational) == 0 {
+		fmt.Println(i18n.T(i18n.NoBehaviorChanges, release))
+		return true, nil
+	}
+
+	printSectionLine(sectionBehaviorChanges, "%s", i18n.T(i18n.BehaviorChangesHeader, release))
+	for _, change := range informational {
+		printSectionLine(sectionBehaviorChanges, "%v  %s", emoji.Information, change)
+	}
+	if len(breaking) != 0 {
+		color.Red("%s", i18n.T(i18n.BreakingChangesCount, emoji.StopSign, len(breaking)))
+	}
+	for _, change := range breaking {
+		color.Red("%v  [%s] %s", emoji.StopSign, matchingKeyword(change, breakingKeywords), change)
+		fmt.Print(i18n.T(i18n.AcknowledgeBreakingChange))
+		cont, err := promptForContinue(reader)
+		if err != nil || !cont {
+			return false, err
+		}
+	}
+	return promptForContinue(reader)
+}
+
+// matchingKeyword returns the first of keywords text contains, ignoring case, or an empty string when it contains
+// none of them.
+func matchingKeyword(text string, keywords []string) string {
+	lowerText := strings.ToLower(text)
+	for _, keyword := range keywords {
+		if keyword != "" && strings.Contains(lowerText, strings.ToLower(keyword)) {
+			return keyword
+		}
+	}
+	return ""
+}
+
+func displayInstallNotes(release string, installNotes []string, reader *prompter) (bool, error) {
+	var displayedOpeningMessage bool
+
+	for _, note := range installNotes {
+		if note == "" || note == "-->" {
+			continue
+		}
+		if !displayedOpeningMessage {
+			printSectionLine(sectionInstallNotes, "%s", i18n.T(i18n.InstallNotesHeader, release))
+			displayedOpeningMessage = true
+		}
+		printSectionLine(sectionInstallNotes, "%v %s", emoji.Memo, note)
+	}
+	if !displayedOpeningMessage {
+		fmt.Println(i18n.T(i18n.NoInstallNotes, release))
+		return true, nil
+	}
+	return promptForContinue(reader)
+}
+
+func displayKnownIssues(release string, knownIssues []string, reader *prompter) ([]string, bool, error) {
+	var issues []string
+	for _, issue := range knownIssues {
+		if issue == "" || issue == "-->" {
+			continue
+		}
+		issues = append(issues, issue)
+	}
+	if len(issues) == 0 {
+		fmt.Println(i18n.T(i18n.NoKnownIssues, release))
+		return nil, true, nil
+	}
+
+	var acknowledged []string
+	printSectionLine(sectionKnownIssues, "%s", i18n.T(i18n.KnownIssuesHeader, len(issues), release))
+	for index, issue := range issues {
+		fmt.Println(i18n.T(i18n.KnownIssueProgress, index+1, len(issues)))
+		printSectionLine(sectionKnownIssues, "%v  %s", emoji.RaisedHand, issue)
+		reference := issueReference(issue)
+		var cont bool
+		var err error
+		if reference != "" {
+			cont, err = promptForIssueNumber(reference, reader)
+		} else {
+			fmt.Print(i18n.T(i18n.AcknowledgeKnownIssue))
+			cont, err = promptForContinue(reader)
+		}
+		if err != nil {
+			return acknowledged, false, err
+		}
+		if !cont {
+			return acknowledged, false, nil
+		}
+		if reference != "" {
+			acknowledged = append(acknowledged, fmt.Sprintf("[#%s] %s", reference, strings.TrimSpace(issue)))
+		} else {
+			acknowledged = append(acknowledged, strings.TrimSpace(issue))
+		}
+	}
+	return acknowledged, true, nil
+}
+
+// issueReference returns the number of the first GitHub issue the known issue links or refers to, e.g. #12345, or an
+// empty string when it has none.
+func issueReference(issue string) string {
+	match := issueReferenceReg.FindStringSubmatch(issue)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// promptForIssueNumber asks the user to acknowledge a known issue by entering its number, so the acknowledgement is
+// tied to the specific issue. Entering n stops instead.
+func promptForIssueNumber(reference string, reader *prompter) (bool, error) {
+	if reader.assumeYes {
+		fmt.Println(i18n.T(i18n.AcknowledgeIssueNumber, reference) + reference)
+		return true, nil
+	}
+
+	for {
+		fmt.Print(i18n.T(i18n.AcknowledgeIssueNumber, reference))
+		answer, err := reader.ReadString('\n')
+		if err != nil {
+			return false, err
+		}
+
+		answer = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(answer)), "#")
+		if answer == reference {
+			return true, nil
+		}
+		if answer == "n" {
+			return false, nil
+		}
+		fmt.Printf("\n%s\n", i18n.T(i18n.InvalidInput))
+	}
+}
+
+// releaseNotesSource returns the raw GitHub release API response for a rancher release.
+type releaseNotesSource interface {
+	fetchReleaseNotes(release string) ([]byte, error)
+}
+
+// githubReleaseNotes fetches release notes from the GitHub API. A nil client is http.DefaultClient, which honors the
+// proxy environment variables.
+type githubReleaseNotes struct {
+	client *http.Client
+}
+
+func (g githubReleaseNotes) fetchReleaseNotes(release string) ([]byte, error) {
+	client := g.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	releaseURL := fmt.Sprintf("%sv%s", ghReleaseNotesAPIPrefix, release)
+	resp, err := client.Get(releaseURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+// fetchAllReleaseNotes fetches the notes of releases with a pool of concurrency workers. The results are in the order
+// of releases.
+func fetchAllReleaseNotes(source releaseNotesSource, releases []string, concurrency int) ([]*releasenotes.Release, []error) {
+	githubReleases := make([]*releasenotes.Release, len(releases))
+	errs := make([]error, len(releases))
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < concurrency && worker < len(releases); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				githubReleases[index], errs[index] = getReleaseNotes(source, releases[index])
+			}
+		}()
+	}
+	for index := range releases {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+	return githubReleases, errs
+}
+
+func getReleaseNotes(source releaseNotesSource, release string) (*releasenotes.Release, error) {
+	bodyBytes, err := source.fetchReleaseNotes(release)
+	if err != nil {
+		return nil, err
+	}
+
+	githubRelease, err := releasenotes.Parse(bodyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get release notes for rancher [%s]: %w", release, err)
+	}
+	return githubRelease, nil
+}
+
+// parseNotesSections returns the notes between header1 and the following header2. It is empty when either header is
+// missing, and when header2 only appears before header1 a warning is shown as the notes are not laid out as expected.
+func parseNotesSections(header1, header2, notes string) (string, error) {
+	startIndex := strings.Index(notes, header1)
+	stopIndex := strings.Index(notes, header2)
+	if startIndex == -1 || stopIndex == -1 {
+		return "", nil
+	}
+
+	bodyStart := startIndex + len(header1)
+	stopOffset := strings.Index(notes[bodyStart:], header2)
+	if stopOffset == -1 {
+		color.Yellow("%s", i18n.T(i18n.SectionOutOfOrder, emoji.Warning, strings.TrimLeft(header1, "# "), strings.TrimLeft(header2, "# ")))
+		return "", nil
+	}
+	sectionBody := notes[bodyStart : bodyStart+stopOffset]
+	sectionBody = strings.ReplaceAll(sectionBody, "\r\n", "")
+
+	return sectionBody, nil
+}
+
+func parseBulletPoints(section string) []string {
+	lines := strings.Split(section, "- ")
+	bullets := make([]string, 0)
+	for _, line := range lines {
+		bullets = append(bullets, line)
+	}
+	return bullets
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
+)
+
+func TestParseNotesSections(t *testing.T) {
+	const (
+		start = "# Known Issues"
+		stop  = "# Versions"
+	)
+	tests := []struct {
+		name  string
+		notes string
+		want  string
+	}{
+		{
+			name:  "in order",
+			notes: "# Known Issues\n- issue one\n- issue two\n# Versions\n- v2.7.5",
+			want:  "\n- issue one\n- issue two\n",
+		},
+		{
+			name:  "carriage returns",
+			notes: "# Known Issues\r\n- issue one\r\n# Versions",
+			want:  "- issue one",
+		},
+		{
+			name:  "empty section",
+			notes: "# Known Issues# Versions",
+			want:  "",
+		},
+		{
+			name:  "reordered",
+			notes: "# Versions\n- v2.7.5\n# Known Issues\n- issue one\n",
+			want:  "",
+		},
+		{
+			name:  "end header before and after",
+			notes: "# Versions\n- v2.7.4\n# Known Issues\n- issue one\n# Versions\n- v2.7.5",
+			want:  "\n- issue one\n",
+		},
+		{
+			name:  "missing start header",
+			notes: "# Major Bug Fixes\n- fix one\n# Versions\n- v2.7.5",
+			want:  "",
+		},
+		{
+			name:  "missing end header",
+			notes: "# Known Issues\n- issue one\n",
+			want:  "",
+		},
+		{
+			name:  "missing both headers",
+			notes: "- issue one\n",
+			want:  "",
+		},
+		{
+			name:  "empty notes",
+			notes: "",
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseNotesSections(start, stop, tt.notes)
+			if err != nil {
+				t.Fatalf("parseNotesSections() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseNotesSections() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuietWalkthrough(t *testing.T) {
+	notes := []releaseNotes{
+		{version: "2.7.4"},
+		{version: "2.7.5", sections: map[string][]string{
+			sectionBugfixes:    {"fixed the hidden bug"},
+			sectionKnownIssues: {"the hidden known issue"},
+		}},
+	}
+	var cont bool
+	var acknowledged []string
+	var err error
+	out := captureOutput(t, func() {
+		cont, acknowledged, err = quietWalkthrough(walkthroughRelevantNotes)(notes, noteSections, time.Time{}, nil, false, newPrompter(strings.NewReader(""), true), nil)
+	})
+	if err != nil || !cont {
+		t.Fatalf("quietWalkthrough() = %t, %v, want to continue", cont, err)
+	}
+	if want := []string{"the hidden known issue"}; !reflect.DeepEqual(acknowledged, want) {
+		t.Errorf("acknowledged = %q, want %q", acknowledged, want)
+	}
+	if strings.Contains(out, "hidden") {
+		t.Errorf("quiet walkthrough printed the notes:\n%s", out)
+	}
+	if want := i18n.T(i18n.QuietNotesSummary, 1, "2.7.4", "2.7.5", 1); strings.TrimSpace(out) != want {
+		t.Errorf("quiet walkthrough printed %q, want %q", out, want)
+	}
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rmweir/rancher-upgrader/internal/cleanup"
+	"github.com/rmweir/rancher-upgrader/internal/clock"
+	"github.com/rmweir/rancher-upgrader/internal/filelock"
+	"github.com/rmweir/rancher-upgrader/internal/releasenotes"
+)
+
+// notesCacheTTL is how long cached release notes are used before they are fetched again. Notes are still edited
+// after a release, known issues in particular, so they are not cached forever.
+const notesCacheTTL = 24 * time.Hour
+
+// cachedReleaseNotes keeps the release notes fetched from source in dir. Writes are serialized with an advisory lock
+// and land with an atomic rename, so concurrent runs sharing dir never read a partially written entry.
+type cachedReleaseNotes struct {
+	source releaseNotesSource
+	dir    string
+	clock  clock.Clock
+}
+
+func (c cachedReleaseNotes) fetchReleaseNotes(release string) ([]byte, error) {
+	entryPath := filepath.Join(c.dir, release+".json")
+	if info, err := os.Stat(entryPath); err == nil && c.clock.Now().Sub(info.ModTime()) < notesCacheTTL {
+		if data, err := os.ReadFile(entryPath); err == nil {
+			return data, nil
+		}
+	}
+
+	data, err := c.source.fetchReleaseNotes(release)
+	if err != nil {
+		return nil, err
+	}
+	// error payloads are returned as is but never cached
+	if _, err := releasenotes.Parse(data); err != nil {
+		return data, nil
+	}
+	return data, c.write(entryPath, data)
+}
+
+func (c cachedReleaseNotes) write(entryPath string, data []byte) (err error) {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+
+	releaseLock, err := filelock.Lock(filepath.Join(c.dir, ".lock"))
+	if err != nil {
+		return err
+	}
+	var unlockErr error
+	unlock := cleanup.Register(func() { unlockErr = releaseLock() })
+	defer func() {
+		unlock()
+		if unlockErr != nil && err == nil {
+			err = unlockErr
+		}
+	}()
+
+	tmp, err := os.CreateTemp(c.dir, filepath.Base(entryPath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer cleanup.Register(func() { os.Remove(tmp.Name()) })()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), entryPath)
+}
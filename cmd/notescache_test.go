@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rmweir/rancher-upgrader/internal/clock"
+)
+
+// countingNotesSource serves the same release notes for every release and counts the fetches.
+type countingNotesSource struct {
+	mu      sync.Mutex
+	fetches int
+	data    []byte
+}
+
+func (s *countingNotesSource) fetchReleaseNotes(release string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetches++
+	return s.data, nil
+}
+
+// fixedClock always tells the same time.
+type fixedClock struct {
+	now time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
+
+func (c fixedClock) Sleep(time.Duration) {}
+
+func testReleaseNotes(release, body string) []byte {
+	return []byte(fmt.Sprintf(`{"tag_name": %q, "html_url": "https://github.com/rancher/rancher/releases/tag/%s", "body": %q}`, release, release, body))
+}
+
+func TestCachedReleaseNotesConcurrentWrites(t *testing.T) {
+	dir := t.TempDir()
+	entryPath := filepath.Join(dir, "v2.7.5.json")
+	const writers = 8
+	payloads := make(map[string]bool, writers)
+	for i := 0; i < writers; i++ {
+		// large enough that interleaved writes would show up as a torn entry
+		payloads[string(testReleaseNotes("v2.7.5", strings.Repeat(fmt.Sprintf("writer %d\n", i), 10000)))] = true
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for payload := range payloads {
+		wg.Add(1)
+		go func(payload []byte) {
+			defer wg.Done()
+			// every writer has its own cache, as concurrent runs sharing the directory would
+			cache := cachedReleaseNotes{dir: dir, clock: clock.Real{}}
+			errs <- cache.write(entryPath, payload)
+		}([]byte(payload))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("write() unexpected error: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(entryPath)
+	if err != nil {
+		t.Fatalf("failed to read the cache entry: %v", err)
+	}
+	if !payloads[string(data)] {
+		t.Errorf("cache entry of %d bytes is not any single writer's release notes", len(data))
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	if got := strings.Join(names, ","); got != ".lock,v2.7.5.json" {
+		t.Errorf("cache directory holds %s, want only the lock and the entry", got)
+	}
+}
+
+func TestCachedReleaseNotesTTL(t *testing.T) {
+	now := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		age         time.Duration
+		wantFetches int
+	}{
+		{name: "fresh entry", age: time.Hour, wantFetches: 0},
+		{name: "stale entry", age: notesCacheTTL + time.Hour, wantFetches: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			entryPath := filepath.Join(dir, "v2.7.5.json")
+			cached := testReleaseNotes("v2.7.5", "cached notes")
+			if err := os.WriteFile(entryPath, cached, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(entryPath, now.Add(-tt.age), now.Add(-tt.age)); err != nil {
+				t.Fatal(err)
+			}
+
+			source := &countingNotesSource{data: testReleaseNotes("v2.7.5", "edited notes")}
+			cache := cachedReleaseNotes{source: source, dir: dir, clock: fixedClock{now: now}}
+			data, err := cache.fetchReleaseNotes("v2.7.5")
+			if err != nil {
+				t.Fatalf("fetchReleaseNotes() unexpected error: %v", err)
+			}
+			if source.fetches != tt.wantFetches {
+				t.Errorf("fetchReleaseNotes() fetched %d times, want %d", source.fetches, tt.wantFetches)
+			}
+			want := cached
+			if tt.wantFetches != 0 {
+				want = source.data
+			}
+			if string(data) != string(want) {
+				t.Errorf("fetchReleaseNotes() = %s, want %s", data, want)
+			}
+			if stored, err := os.ReadFile(entryPath); err != nil || string(stored) != string(want) {
+				t.Errorf("cache entry = %s, %v, want %s", stored, err, want)
+			}
+		})
+	}
+}
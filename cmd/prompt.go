@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+)
+
+// prompter reads the user's answers to prompts. With assumeYes set, confirmations and acknowledgements are answered
+// with yes without reading anything, so unattended runs never block on stdin.
+type prompter struct {
+	*bufio.Reader
+	assumeYes bool
+}
+
+func newPrompter(in io.Reader, assumeYes bool) *prompter {
+	return &prompter{Reader: bufio.NewReader(in), assumeYes: assumeYes}
+}
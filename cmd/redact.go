@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+const redactedValue = "***"
+
+var defaultRedactKeys = []string{"bootstrapPassword", "password", "token", "secretKey", "accessKey", "privateKey"}
+
+func redactKeysFlag() cli.Flag {
+	return &cli.StringSliceFlag{
+		Name:  "redact-keys",
+		Usage: "Value keys or dotted paths, e.g. bootstrapPassword or auditLog.token, to show as *** when displaying values",
+		Value: cli.NewStringSlice(defaultRedactKeys...),
+	}
+}
+
+// redactValues returns a copy of values where every key matching one of keys, either by name or by its full dotted
+// path, is replaced with ***. Matching is case-insensitive. values itself is left untouched.
+func redactValues(values map[string]interface{}, keys []string) map[string]interface{} {
+	if len(keys) == 0 || values == nil {
+		return values
+	}
+	return redactMap("", values, keys)
+}
+
+func redactMap(path string, values map[string]interface{}, keys []string) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+		if matchesRedactKey(key, keyPath, keys) {
+			redacted[key] = redactedValue
+			continue
+		}
+		redacted[key] = redactValue(keyPath, value, keys)
+	}
+	return redacted
+}
+
+func redactValue(path string, value interface{}, keys []string) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		return redactMap(path, typed, keys)
+	case []interface{}:
+		redacted := make([]interface{}, len(typed))
+		for i, item := range typed {
+			redacted[i] = redactValue(path, item, keys)
+		}
+		return redacted
+	default:
+		return value
+	}
+}
+
+func matchesRedactKey(key, keyPath string, keys []string) bool {
+	for _, redactKey := range keys {
+		if strings.EqualFold(redactKey, key) || strings.EqualFold(redactKey, keyPath) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rmweir/rancher-upgrader/internal/helm"
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
+	"github.com/urfave/cli/v2"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// rancherReleaseFinder discovers the installed rancher releases, only in --namespace when it is set.
+type rancherReleaseFinder interface {
+	FindRancherReleases() ([]*release.Release, error)
+}
+
+// selectedRelease is the rancher release a command operates on.
+type selectedRelease struct {
+	Name      string
+	Namespace string
+	Version   string
+	Status    release.Status
+	// Release is the latest revision of the release
+	Release *release.Release
+}
+
+// findRancherRelease is how every command picks the rancher release it operates on: the releases finder discovers
+// are narrowed down with --release-name and chosen from as selectRancherRelease does.
+func findRancherRelease(ctx *cli.Context, finder rancherReleaseFinder, interactive bool, reader *prompter, out io.Writer) (selectedRelease, error) {
+	releases, err := finder.FindRancherReleases()
+	if err != nil {
+		return selectedRelease{}, err
+	}
+	rel, err := selectRancherRelease(releases, ctx.String("release-name"), interactive, reader, out)
+	if err != nil {
+		return selectedRelease{}, err
+	}
+	selected := selectedRelease{Name: rel.Name, Namespace: rel.Namespace, Release: rel}
+	if rel.Chart != nil && rel.Chart.Metadata != nil {
+		selected.Version = rel.Chart.Metadata.Version
+	}
+	if rel.Info != nil {
+		selected.Status = rel.Info.Status
+	}
+	return selected, nil
+}
+
+// selectRancherRelease picks the release to operate on. A release name narrows the candidates, and if more than one
+// candidate remains the user is asked to choose, unless interactive is false in which case an error listing the
+// candidates is returned. Releases in helm.RancherNamespace are listed and offered first.
+func selectRancherRelease(releases []*release.Release, releaseName string, interactive bool, reader *prompter, out io.Writer) (*release.Release, error) {
+	if len(releases) == 0 {
+		return nil, fmt.Errorf("rancher release could not be found")
+	}
+	releases = append([]*release.Release(nil), releases...)
+	sort.SliceStable(releases, func(i, j int) bool {
+		return releases[i].Namespace == helm.RancherNamespace && releases[j].Namespace != helm.RancherNamespace
+	})
+	candidates := releases
+	if releaseName != "" {
+		candidates = nil
+		for _, rel := range releases {
+			if rel.Name == releaseName {
+				candidates = append(candidates, rel)
+			}
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("no rancher release named [%s] found, candidates are: %s", releaseName, formatReleases(releases))
+		}
+	}
+
+	if len(candidates) == 1 {
+		fmt.Fprintln(out, i18n.T(i18n.FoundRelease, candidates[0].Name, candidates[0].Namespace, formatChartVersion(candidates[0].Chart.Metadata)))
+		return candidates[0], nil
+	}
+
+	if !interactive {
+		return nil, fmt.Errorf("found multiple rancher releases: %s, select one with --release-name and --namespace", formatReleases(candidates))
+	}
+
+	fmt.Fprintln(out, i18n.T(i18n.FoundMultipleReleases, len(candidates)))
+	for i, rel := range candidates {
+		fmt.Fprintf(out, "%d. %s:%s at %s\n", i+1, rel.Name, rel.Namespace, formatChartVersion(rel.Chart.Metadata))
+	}
+	for {
+		fmt.Fprint(out, i18n.T(i18n.SelectReleasePrompt))
+		answer, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		selection, err := strconv.Atoi(strings.TrimSpace(answer))
+		if err == nil && selection >= 1 && selection <= len(candidates) {
+			return candidates[selection-1], nil
+		}
+		fmt.Fprintf(out, "\n%s\n", i18n.T(i18n.InvalidInput))
+	}
+}
+
+// formatChartVersion describes both the chart version and the rancher version it ships, which usually but not always
+// match.
+func formatChartVersion(metadata *chart.Metadata) string {
+	if metadata.AppVersion == "" {
+		return i18n.T(i18n.ChartVersion, metadata.Version)
+	}
+	return i18n.T(i18n.ChartAndAppVersion, metadata.Version, metadata.AppVersion)
+}
+
+func formatReleases(releases []*release.Release) string {
+	names := make([]string, len(releases))
+	for i, rel := range releases {
+		names[i] = fmt.Sprintf("%s:%s", rel.Name, rel.Namespace)
+	}
+	return strings.Join(names, ", ")
+}
+
+// isInteractive reports whether stdin is attached to a terminal.
+func isInteractive() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func testRelease(name, namespace, version string) *release.Release {
+	return &release.Release{
+		Name:      name,
+		Namespace: namespace,
+		Version:   1,
+		Info:      &release.Info{Status: release.StatusDeployed},
+		Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: "rancher", Version: version, AppVersion: "v" + version}},
+	}
+}
+
+func TestSelectRancherRelease(t *testing.T) {
+	single := []*release.Release{testRelease("rancher", "cattle-system", "2.7.5")}
+	several := []*release.Release{
+		testRelease("rancher", "cattle-system", "2.7.5"),
+		testRelease("rancher", "rancher-test", "2.8.0"),
+		testRelease("rancher-canary", "cattle-system", "2.8.1"),
+	}
+
+	tests := []struct {
+		name        string
+		releases    []*release.Release
+		releaseName string
+		interactive bool
+		input       string
+		// want is the selected release as name:namespace
+		want    string
+		wantErr string
+	}{
+		{name: "none", releases: nil, wantErr: "rancher release could not be found"},
+		{name: "none interactive", releases: nil, interactive: true, wantErr: "rancher release could not be found"},
+		{name: "one", releases: single, want: "rancher:cattle-system"},
+		{name: "one interactive", releases: single, interactive: true, want: "rancher:cattle-system"},
+		{
+			name:     "several",
+			releases: several,
+			wantErr: "found multiple rancher releases: rancher:cattle-system, rancher-canary:cattle-system, rancher:rancher-test, " +
+				"select one with --release-name and --namespace",
+		},
+		{name: "several interactive", releases: several, interactive: true, input: "3\n", want: "rancher:rancher-test"},
+		{name: "several interactive retries invalid input", releases: several, interactive: true, input: "0\nfirst\n2\n", want: "rancher-canary:cattle-system"},
+		{name: "several interactive without an answer", releases: several, interactive: true, input: "", wantErr: io.EOF.Error()},
+		{name: "cattle-system offered first", releases: several[1:], interactive: true, input: "1\n", want: "rancher-canary:cattle-system"},
+		{name: "several narrowed to one by name", releases: several, releaseName: "rancher-canary", want: "rancher-canary:cattle-system"},
+		{
+			name:        "several narrowed by name",
+			releases:    several,
+			releaseName: "rancher",
+			wantErr:     "found multiple rancher releases: rancher:cattle-system, rancher:rancher-test, select one with --release-name and --namespace",
+		},
+		{name: "several narrowed by name interactive", releases: several, releaseName: "rancher", interactive: true, input: "1\n", want: "rancher:cattle-system"},
+		{
+			name:        "unknown name",
+			releases:    several,
+			releaseName: "rancher-prod",
+			interactive: true,
+			wantErr:     "no rancher release named [rancher-prod] found, candidates are: rancher:cattle-system, rancher-canary:cattle-system, rancher:rancher-test",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectRancherRelease(tt.releases, tt.releaseName, tt.interactive, newPrompter(strings.NewReader(tt.input), false), io.Discard)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("selectRancherRelease() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectRancherRelease() unexpected error: %v", err)
+			}
+			if selected := got.Name + ":" + got.Namespace; selected != tt.want {
+				t.Errorf("selectRancherRelease() = %s, want %s", selected, tt.want)
+			}
+		})
+	}
+}
+
+type releasesFinder struct {
+	releases []*release.Release
+	err      error
+}
+
+func (f releasesFinder) FindRancherReleases() ([]*release.Release, error) {
+	return f.releases, f.err
+}
+
+// commandContext parses args with the flags of command, the way the command's action sees them.
+func commandContext(t *testing.T, command *cli.Command, args ...string) *cli.Context {
+	t.Helper()
+	set := flag.NewFlagSet(command.Name, flag.ContinueOnError)
+	for _, f := range command.Flags {
+		if err := f.Apply(set); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := set.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return cli.NewContext(cli.NewApp(), set, nil)
+}
+
+func TestFindRancherRelease(t *testing.T) {
+	failed := testRelease("rancher", "rancher-test", "2.8.0")
+	failed.Version = 4
+	failed.Info.Status = release.StatusFailed
+	releases := []*release.Release{testRelease("rancher", "cattle-system", "2.7.5"), failed, testRelease("rancher-canary", "cattle-system", "2.8.1")}
+
+	tests := []struct {
+		name        string
+		finder      rancherReleaseFinder
+		args        []string
+		interactive bool
+		input       string
+		want        selectedRelease
+		wantErr     string
+	}{
+		{
+			name:   "by name",
+			finder: releasesFinder{releases: releases},
+			args:   []string{"--release-name", "rancher-canary"},
+			want:   selectedRelease{Name: "rancher-canary", Namespace: "cattle-system", Version: "2.8.1", Status: release.StatusDeployed},
+		},
+		{
+			name:        "chosen interactively",
+			finder:      releasesFinder{releases: releases},
+			args:        []string{"--release-name", "rancher"},
+			interactive: true,
+			input:       "2\n",
+			want:        selectedRelease{Name: "rancher", Namespace: "rancher-test", Version: "2.8.0", Status: release.StatusFailed},
+		},
+		{
+			name:    "several without a name",
+			finder:  releasesFinder{releases: releases},
+			wantErr: "found multiple rancher releases: rancher:cattle-system, rancher-canary:cattle-system, rancher:rancher-test, select one with --release-name and --namespace",
+		},
+		{
+			name:    "finder error",
+			finder:  releasesFinder{err: errors.New("rancher release could not be found")},
+			wantErr: "rancher release could not be found",
+		},
+		{
+			name:   "replayed session",
+			finder: &sessionReplay{session: &session{Releases: releases}},
+			args:   []string{"--release-name", "rancher-canary"},
+			want:   selectedRelease{Name: "rancher-canary", Namespace: "cattle-system", Version: "2.8.1", Status: release.StatusDeployed},
+		},
+	}
+
+	// every command selects the release the same way from the same flags
+	commands := []*cli.Command{UpgradeCommand(), ValuesCommand(), ValidateCommand(), ListCommand(), HistoryCommand(), RollbackCommand()}
+	for _, command := range commands {
+		for _, tt := range tests {
+			t.Run(command.Name+"/"+tt.name, func(t *testing.T) {
+				ctx := commandContext(t, command, tt.args...)
+				got, err := findRancherRelease(ctx, tt.finder, tt.interactive, newPrompter(strings.NewReader(tt.input), false), io.Discard)
+				if tt.wantErr != "" {
+					if err == nil || err.Error() != tt.wantErr {
+						t.Fatalf("findRancherRelease() error = %v, want %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("findRancherRelease() unexpected error: %v", err)
+				}
+				if got.Release == nil || got.Release.Name != tt.want.Name || got.Release.Namespace != tt.want.Namespace {
+					t.Errorf("findRancherRelease() Release = %v, want release %s:%s", got.Release, tt.want.Name, tt.want.Namespace)
+				}
+				got.Release = nil
+				if got != tt.want {
+					t.Errorf("findRancherRelease() = %+v, want %+v", got, tt.want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"time"
+
+	"github.com/rmweir/rancher-upgrader/internal/diff"
+)
+
+const (
+	reportFormatHTML = "html"
+	reportFormatJSON = "json"
+)
+
+// upgradeReport is the upgrade plan written with --report for change management: the version path, the release notes
+// reviewed along it and the default values changes of each hop. Like the audit log it never carries chart values.
+type upgradeReport struct {
+	RunID       string      `json:"runId"`
+	GeneratedAt time.Time   `json:"generatedAt"`
+	Release     string      `json:"release"`
+	Namespace   string      `json:"namespace"`
+	DryRun      bool        `json:"dryRun"`
+	Hops        []reportHop `json:"hops"`
+}
+
+// reportHop is a single upgrade of the plan.
+type reportHop struct {
+	FromVersion             string          `json:"fromVersion"`
+	ToVersion               string          `json:"toVersion"`
+	NotesSkipped            bool            `json:"notesSkipped,omitempty"`
+	Releases                []reportRelease `json:"releases"`
+	AcknowledgedKnownIssues []string        `json:"acknowledgedKnownIssues,omitempty"`
+	DefaultValues           []diff.Change   `json:"defaultValues"`
+}
+
+// reportRelease holds the notes of a release the hop upgrades across, sections in release notes order.
+type reportRelease struct {
+	Version     string          `json:"version"`
+	URL         string          `json:"url"`
+	PublishedAt time.Time       `json:"publishedAt,omitempty"`
+	FetchError  string          `json:"fetchError,omitempty"`
+	Sections    []reportSection `json:"sections"`
+}
+
+type reportSection struct {
+	Name    string   `json:"name"`
+	Bullets []string `json:"bullets"`
+}
+
+// addHop records the notes of an upgrade from notes[0] to the last of notes, the installed release's own notes are
+// left out.
+func (r *upgradeReport) addHop(notes []releaseNotes, sections []noteSection) *reportHop {
+	hop := reportHop{FromVersion: notes[0].version, ToVersion: notes[len(notes)-1].version}
+	for _, note := range notes[1:] {
+		release := reportRelease{Version: note.version, URL: note.url, PublishedAt: note.publishedAt}
+		if note.fetchErr != nil {
+			release.FetchError = note.fetchErr.Error()
+		}
+		for _, section := range sections {
+			if bullets := nonEmptyBullets(note.sections[section.name]); len(bullets) != 0 {
+				release.Sections = append(release.Sections, reportSection{Name: section.name, Bullets: bullets})
+			}
+		}
+		hop.Releases = append(hop.Releases, release)
+	}
+	r.Hops = append(r.Hops, hop)
+	return &r.Hops[len(r.Hops)-1]
+}
+
+// addSkippedHop adds the upgrade from fromVersion to toVersion whose notes were skipped with --skip-notes.
+func (r *upgradeReport) addSkippedHop(fromVersion, toVersion string) *reportHop {
+	r.Hops = append(r.Hops, reportHop{FromVersion: fromVersion, ToVersion: toVersion, NotesSkipped: true})
+	return &r.Hops[len(r.Hops)-1]
+}
+
+func nonEmptyBullets(bullets []string) []string {
+	var kept []string
+	for _, bullet := range bullets {
+		if bullet != "" && bullet != "-->" {
+			kept = append(kept, bullet)
+		}
+	}
+	return kept
+}
+
+func writeReport(path, format string, report upgradeReport) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	switch format {
+	case reportFormatJSON:
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	case reportFormatHTML:
+		err = reportTemplate.Execute(file, report)
+	default:
+		return fmt.Errorf("unsupported report format [%s], must be one of: %s, %s", format, reportFormatHTML, reportFormatJSON)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write report [%s]: %w", path, err)
+	}
+	return file.Close()
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return "unknown"
+		}
+		return t.UTC().Format("2006-01-02 15:04 MST")
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Rancher upgrade plan: {{.Release}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 960px; margin: 2em auto; color: #1f2328; }
+h1 { border-bottom: 2px solid #2453ff; padding-bottom: .3em; }
+h2 { margin-top: 2em; border-bottom: 1px solid #d0d7de; }
+table { border-collapse: collapse; width: 100%; margin: 1em 0; }
+th, td { border: 1px solid #d0d7de; padding: .4em .6em; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+code { font-family: SFMono-Regular, Consolas, monospace; font-size: .9em; }
+.added { color: #1a7f37; } .removed { color: #cf222e; } .modified { color: #9a6700; }
+.path { font-size: 1.2em; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>Rancher upgrade plan</h1>
+<table>
+<tr><th>Release</th><td>{{.Release}} in namespace {{.Namespace}}</td></tr>
+{{- if .Hops}}
+<tr><th>Version path</th><td class="path">{{(index .Hops 0).FromVersion}}{{range .Hops}} &rarr; {{.ToVersion}}{{end}}</td></tr>
+{{- end}}
+<tr><th>Dry run</th><td>{{.DryRun}}</td></tr>
+<tr><th>Generated</th><td>{{date .GeneratedAt}}</td></tr>
+<tr><th>Run ID</th><td><code>{{.RunID}}</code></td></tr>
+</table>
+{{- range .Hops}}
+<h2>Upgrade {{.FromVersion}} &rarr; {{.ToVersion}}</h2>
+{{- if .NotesSkipped}}
+<p class="modified">The release notes were skipped with --skip-notes, they were reviewed in an earlier run.</p>
+{{- end}}
+{{- range .Releases}}
+<h3><a href="{{.URL}}">Rancher {{.Version}}</a> <small>published {{date .PublishedAt}}</small></h3>
+{{- if .FetchError}}
+<p class="removed">The notes could not be fetched and were not reviewed: {{.FetchError}}</p>
+{{- else}}
+{{- range .Sections}}
+<h4>{{.Name}}</h4>
+<ul>{{range .Bullets}}<li>{{.}}</li>{{end}}</ul>
+{{- else}}
+<p>No notes in the reviewed sections.</p>
+{{- end}}
+{{- end}}
+{{- end}}
+{{- if .AcknowledgedKnownIssues}}
+<h3>Acknowledged known issues</h3>
+<ul>{{range .AcknowledgedKnownIssues}}<li>{{.}}</li>{{end}}</ul>
+{{- end}}
+<h3>Default values changes</h3>
+{{- if .DefaultValues}}
+<table>
+<tr><th>Change</th><th>Path</th><th>Installed</th><th>Target</th></tr>
+{{- range .DefaultValues}}
+<tr class="{{.Type}}"><td>{{.Type}}</td><td><code>{{.Path}}</code></td><td>{{with .Old}}<code>{{.}}</code>{{end}}</td><td>{{with .New}}<code>{{.}}</code>{{end}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>The default values did not change.</p>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
+	"github.com/urfave/cli/v2"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+type RollbackActionClient struct {
+	helmExecer helmExecer
+}
+
+func RollbackCommand() *cli.Command {
+	flags := append(clusterFlags(),
+		&cli.IntFlag{
+			Name:  "revision",
+			Usage: "Revision to roll the rancher release back to, defaults to the last one deployed before the current revision",
+		},
+		&cli.BoolFlag{
+			Name:    "yes",
+			Aliases: []string{"y"},
+			Usage:   "Roll back without asking for confirmation",
+		},
+	)
+
+	c := &RollbackActionClient{}
+	return &cli.Command{
+		Name:   "rollback",
+		Usage:  "Roll the installed rancher release back to an earlier revision",
+		Action: c.Rollback,
+		Flags:  flags,
+	}
+}
+
+func (r *RollbackActionClient) Rollback(ctx *cli.Context) error {
+	if ctx.String("kubeconfig") == "-" {
+		return fmt.Errorf("--kubeconfig - cannot be used with rollback, stdin is needed to confirm it")
+	}
+	reader := newPrompter(os.Stdin, ctx.Bool("yes"))
+
+	targetRelease, client, cleanup, err := selectScopedRancherRelease(ctx, reader, os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if r.helmExecer == nil {
+		r.helmExecer = client
+	}
+
+	revisions, err := r.helmExecer.History(targetRelease.Namespace, targetRelease.Name)
+	if err != nil {
+		return err
+	}
+	revision, err := rollbackRevision(revisions, targetRelease, ctx.Int("revision"))
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(i18n.T(i18n.ConfirmRollback, targetRelease.Name, targetRelease.Namespace, targetRelease.Version,
+		formatChartVersion(targetRelease.Chart.Metadata), revision.Version, formatChartVersion(revision.Chart.Metadata)))
+	cont, err := promptForContinue(reader)
+	if err != nil || !cont {
+		return err
+	}
+	if err := r.helmExecer.Rollback(targetRelease.Namespace, targetRelease.Name, revision.Version); err != nil {
+		return err
+	}
+	fmt.Println(i18n.T(i18n.RolledBack, targetRelease.Name, revision.Version))
+	return nil
+}
+
+// rollbackRevision returns the revision of revisions to roll targetRelease back to: the one numbered revision, or
+// when revision is 0 the newest revision before targetRelease that was deployed.
+func rollbackRevision(revisions []*release.Release, targetRelease *release.Release, revision int) (*release.Release, error) {
+	if revision == targetRelease.Version {
+		return nil, fmt.Errorf("revision [%d] is the current revision of release [%s]", revision, targetRelease.Name)
+	}
+	for i := len(revisions) - 1; i >= 0; i-- {
+		candidate := revisions[i]
+		if revision != 0 {
+			if candidate.Version == revision {
+				return candidate, nil
+			}
+			continue
+		}
+		if candidate.Version < targetRelease.Version && candidate.Info != nil &&
+			(candidate.Info.Status == release.StatusSuperseded || candidate.Info.Status == release.StatusDeployed) {
+			return candidate, nil
+		}
+	}
+	if revision != 0 {
+		return nil, fmt.Errorf("revision [%d] not found in the history of release [%s]", revision, targetRelease.Name)
+	}
+	return nil, fmt.Errorf("release [%s] has no earlier deployed revision to roll back to", targetRelease.Name)
+}
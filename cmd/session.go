@@ -0,0 +1,424 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ghodss/yaml"
+	"github.com/rmweir/rancher-upgrader/internal/helm"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/repo"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// session is everything an upgrade run saw: the results of every cluster and repo lookup, the fetched release notes
+// and the answers the user gave. A session recorded with --record-session can be replayed with --simulate without a
+// cluster or network access. Values are redacted with --redact-keys and Secret data is masked before recording, so
+// the recorded charts and releases only carry what is needed to replay the prompts.
+type session struct {
+	Listed        []*release.Release            `json:"listed,omitempty"`
+	Releases      []*release.Release            `json:"releases,omitempty"`
+	History       map[string][]*release.Release `json:"history,omitempty"`
+	NextVersions  map[string]string             `json:"nextVersions,omitempty"`
+	UpgradePaths  map[string][]string           `json:"upgradePaths,omitempty"`
+	ChartVersions map[string]*repo.ChartVersion `json:"chartVersions,omitempty"`
+	AppVersions   map[string]*repo.ChartVersion `json:"appVersions,omitempty"`
+	Charts        map[string]*chart.Chart       `json:"charts,omitempty"`
+	Upgrades      []*release.Release            `json:"upgrades,omitempty"`
+	ReleaseNotes  map[string]json.RawMessage    `json:"releaseNotes,omitempty"`
+	Answers       []string                      `json:"answers,omitempty"`
+}
+
+func newSession() *session {
+	return &session{
+		History:       map[string][]*release.Release{},
+		NextVersions:  map[string]string{},
+		UpgradePaths:  map[string][]string{},
+		ChartVersions: map[string]*repo.ChartVersion{},
+		AppVersions:   map[string]*repo.ChartVersion{},
+		Charts:        map[string]*chart.Chart{},
+		ReleaseNotes:  map[string]json.RawMessage{},
+	}
+}
+
+func loadSession(path string) (*session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := newSession()
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse session [%s]: %w", path, err)
+	}
+	return s, nil
+}
+
+func (s *session) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// sessionRecorder passes every call through to the real helm client and release notes source and records the
+// results in the session.
+type sessionRecorder struct {
+	helmExecer
+	notes      releaseNotesSource
+	session    *session
+	redactKeys []string
+	answers    bytes.Buffer
+	// notesMu guards the release notes of the session, which are fetched concurrently
+	notesMu sync.Mutex
+}
+
+// input returns a reader over in that records everything read from it as the session's answers.
+func (r *sessionRecorder) input(in io.Reader) io.Reader {
+	return io.TeeReader(in, &r.answers)
+}
+
+func (r *sessionRecorder) save(path string) error {
+	r.session.Answers = strings.SplitAfter(r.answers.String(), "\n")
+	if last := len(r.session.Answers) - 1; r.session.Answers[last] == "" {
+		r.session.Answers = r.session.Answers[:last]
+	}
+	return r.session.save(path)
+}
+
+// ListReleases records only the name, namespace and chart metadata of the listed releases, the other releases in the
+// cluster are none of the session's business.
+func (r *sessionRecorder) ListReleases() ([]*release.Release, error) {
+	releases, err := r.helmExecer.ListReleases()
+	if err != nil {
+		return nil, err
+	}
+	r.session.Listed = make([]*release.Release, len(releases))
+	for i, rel := range releases {
+		r.session.Listed[i] = &release.Release{Name: rel.Name, Namespace: rel.Namespace}
+		if rel.Chart != nil {
+			r.session.Listed[i].Chart = &chart.Chart{Metadata: rel.Chart.Metadata}
+		}
+	}
+	return releases, nil
+}
+
+func (r *sessionRecorder) FindRancherReleases() ([]*release.Release, error) {
+	releases, err := r.helmExecer.FindRancherReleases()
+	if err != nil {
+		return nil, err
+	}
+	r.session.Releases = r.recordReleases(releases)
+	return releases, nil
+}
+
+func (r *sessionRecorder) History(namespace, releaseName string) ([]*release.Release, error) {
+	revisions, err := r.helmExecer.History(namespace, releaseName)
+	if err != nil {
+		return nil, err
+	}
+	// the history is looked up again before upgrading, replays need the one the base revision was selected from
+	if _, recorded := r.session.History[releaseName]; !recorded {
+		r.session.History[releaseName] = r.recordReleases(revisions)
+	}
+	return revisions, nil
+}
+
+func (r *sessionRecorder) GetNextSupportedRancherChartVersion(currentVersion string) (string, error) {
+	nextVersion, err := r.helmExecer.GetNextSupportedRancherChartVersion(currentVersion)
+	if err != nil {
+		return "", err
+	}
+	r.session.NextVersions[currentVersion] = nextVersion
+	return nextVersion, nil
+}
+
+func (r *sessionRecorder) GetUpgradePath(currentVersion string) ([]string, error) {
+	upgradePath, err := r.helmExecer.GetUpgradePath(currentVersion)
+	if err != nil {
+		return nil, err
+	}
+	r.session.UpgradePaths[currentVersion] = upgradePath
+	return upgradePath, nil
+}
+
+func (r *sessionRecorder) GetRancherChartForVersion(version string) (*repo.ChartVersion, error) {
+	chartVersion, err := r.helmExecer.GetRancherChartForVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	r.session.ChartVersions[version] = chartVersion
+	return chartVersion, nil
+}
+
+func (r *sessionRecorder) GetRancherChartForAppVersion(appVersion string) (*repo.ChartVersion, error) {
+	chartVersion, err := r.helmExecer.GetRancherChartForAppVersion(appVersion)
+	if err != nil {
+		return nil, err
+	}
+	r.session.AppVersions[appVersion] = chartVersion
+	return chartVersion, nil
+}
+
+func (r *sessionRecorder) LoadRancherChart(version string) (*chart.Chart, error) {
+	targetChart, err := r.helmExecer.LoadRancherChart(version)
+	if err != nil {
+		return nil, err
+	}
+	r.session.Charts[version] = r.recordChart(targetChart)
+	return targetChart, nil
+}
+
+func (r *sessionRecorder) Upgrade(rel *release.Release, targetChart *chart.Chart, overrideValues map[string]interface{}, opts helm.UpgradeOptions) (*release.Release, error) {
+	newRelease, err := r.helmExecer.Upgrade(rel, targetChart, overrideValues, opts)
+	if err != nil {
+		return nil, err
+	}
+	r.session.Upgrades = append(r.session.Upgrades, r.recordRelease(newRelease))
+	return newRelease, nil
+}
+
+func (r *sessionRecorder) fetchReleaseNotes(version string) ([]byte, error) {
+	data, err := r.notes.fetchReleaseNotes(version)
+	if err != nil {
+		return nil, err
+	}
+	if json.Valid(data) {
+		r.notesMu.Lock()
+		r.session.ReleaseNotes[version] = data
+		r.notesMu.Unlock()
+	}
+	return data, nil
+}
+
+func (r *sessionRecorder) recordReleases(releases []*release.Release) []*release.Release {
+	recorded := make([]*release.Release, len(releases))
+	for i, rel := range releases {
+		recorded[i] = r.recordRelease(rel)
+	}
+	return recorded
+}
+
+// recordRelease keeps what the prompts show of a release. Hooks are reduced to what is listed of them and the chart to
+// its metadata and default values.
+func (r *sessionRecorder) recordRelease(rel *release.Release) *release.Release {
+	return &release.Release{
+		Name:      rel.Name,
+		Namespace: rel.Namespace,
+		Version:   rel.Version,
+		Info:      rel.Info,
+		Chart:     r.recordChart(rel.Chart),
+		Config:    redactValues(rel.Config, r.redactKeys),
+		Manifest:  redactManifestSecrets(rel.Manifest),
+		Hooks:     recordHooks(rel.Hooks),
+	}
+}
+
+// recordHooks drops the hook manifests, which can carry secrets.
+func recordHooks(hooks []*release.Hook) []*release.Hook {
+	recorded := make([]*release.Hook, len(hooks))
+	for i, hook := range hooks {
+		recorded[i] = &release.Hook{
+			Name:   hook.Name,
+			Kind:   hook.Kind,
+			Path:   hook.Path,
+			Events: hook.Events,
+			Weight: hook.Weight,
+		}
+	}
+	return recorded
+}
+
+func (r *sessionRecorder) recordChart(c *chart.Chart) *chart.Chart {
+	if c == nil {
+		return nil
+	}
+	return &chart.Chart{
+		Metadata: c.Metadata,
+		Values:   redactValues(c.Values, r.redactKeys),
+	}
+}
+
+// redactManifestSecrets masks the data of every Secret in manifest. Other resources are kept as they are.
+func redactManifestSecrets(manifest string) string {
+	if manifest == "" {
+		return ""
+	}
+
+	split := releaseutil.SplitManifests(manifest)
+	keys := make([]string, 0, len(split))
+	for key := range split {
+		keys = append(keys, key)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	docs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		doc := split[key]
+		var object map[string]interface{}
+		if err := yaml.Unmarshal([]byte(doc), &object); err != nil || object["kind"] != "Secret" {
+			docs = append(docs, doc)
+			continue
+		}
+		for _, field := range []string{"data", "stringData"} {
+			data, ok := object[field].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for name := range data {
+				data[name] = redactedValue
+			}
+		}
+		redacted, err := yaml.Marshal(object)
+		if err != nil {
+			continue
+		}
+		docs = append(docs, string(redacted))
+	}
+	return strings.Join(docs, "\n---\n")
+}
+
+// sessionReplay answers every call from a recorded session, it never reaches a cluster or the network.
+type sessionReplay struct {
+	session  *session
+	upgrades int
+}
+
+// input returns a reader that replays the recorded answers one line at a time, echoing each answer to out as it is
+// read so the replayed prompts read the same as the recorded ones.
+func (r *sessionReplay) input(out io.Writer) io.Reader {
+	return &answerReader{answers: r.session.Answers, out: out}
+}
+
+func (r *sessionReplay) ListReleases() ([]*release.Release, error) {
+	return r.session.Listed, nil
+}
+
+func (r *sessionReplay) FindRancherReleases() ([]*release.Release, error) {
+	if len(r.session.Releases) == 0 {
+		return nil, fmt.Errorf("no rancher releases were recorded in the session")
+	}
+	return r.session.Releases, nil
+}
+
+// History replays the history by release name, a session only ever records the one selected release.
+func (r *sessionReplay) History(_, releaseName string) ([]*release.Release, error) {
+	revisions, ok := r.session.History[releaseName]
+	if !ok {
+		return nil, fmt.Errorf("the history of release [%s] was not recorded in the session", releaseName)
+	}
+	return revisions, nil
+}
+
+func (r *sessionReplay) GetNextSupportedRancherChartVersion(currentVersion string) (string, error) {
+	nextVersion, ok := r.session.NextVersions[currentVersion]
+	if !ok {
+		return "", fmt.Errorf("the next supported version after [%s] was not recorded in the session", currentVersion)
+	}
+	return nextVersion, nil
+}
+
+func (r *sessionReplay) GetUpgradePath(currentVersion string) ([]string, error) {
+	upgradePath, ok := r.session.UpgradePaths[currentVersion]
+	if !ok {
+		return nil, fmt.Errorf("the upgrade path from [%s] was not recorded in the session", currentVersion)
+	}
+	return upgradePath, nil
+}
+
+func (r *sessionReplay) GetRancherChartForVersion(version string) (*repo.ChartVersion, error) {
+	chartVersion, ok := r.session.ChartVersions[version]
+	if !ok {
+		return nil, fmt.Errorf("rancher chart version [%s] was not recorded in the session", version)
+	}
+	return chartVersion, nil
+}
+
+func (r *sessionReplay) GetRancherChartForAppVersion(appVersion string) (*repo.ChartVersion, error) {
+	chartVersion, ok := r.session.AppVersions[appVersion]
+	if !ok {
+		return nil, fmt.Errorf("the chart version of rancher [%s] was not recorded in the session", appVersion)
+	}
+	return chartVersion, nil
+}
+
+func (r *sessionReplay) LoadRancherChart(version string) (*chart.Chart, error) {
+	targetChart, ok := r.session.Charts[version]
+	if !ok {
+		return nil, fmt.Errorf("rancher chart [%s] was not recorded in the session", version)
+	}
+	return targetChart, nil
+}
+
+func (r *sessionReplay) Upgrade(_ *release.Release, _ *chart.Chart, _ map[string]interface{}, _ helm.UpgradeOptions) (*release.Release, error) {
+	if r.upgrades >= len(r.session.Upgrades) {
+		return nil, fmt.Errorf("no more upgrades were recorded in the session")
+	}
+	newRelease := r.session.Upgrades[r.upgrades]
+	r.upgrades++
+	return newRelease, nil
+}
+
+// Rollback has nothing to roll back, the replayed upgrades were never applied.
+func (r *sessionReplay) Rollback(_, _ string, _ int) error {
+	return nil
+}
+
+// Render renders nothing, the recorded charts carry no templates.
+func (r *sessionReplay) Render(_ *release.Release, _ *chart.Chart, _ map[string]interface{}) (string, error) {
+	return "", nil
+}
+
+// Deployment is never called, replayed upgrades are not snapshotted.
+func (r *sessionReplay) Deployment(namespace, name string) (*appsv1.Deployment, error) {
+	return nil, fmt.Errorf("deployment [%s] in namespace [%s] was not recorded in the session", name, namespace)
+}
+
+// ConfigMap is never called, replayed upgrades are not snapshotted.
+func (r *sessionReplay) ConfigMap(namespace, name string) (*corev1.ConfigMap, error) {
+	return nil, fmt.Errorf("configmap [%s] in namespace [%s] was not recorded in the session", name, namespace)
+}
+
+// Secret is never called, values are never read from the cluster in a simulation.
+func (r *sessionReplay) Secret(namespace, name string) (*corev1.Secret, error) {
+	return nil, fmt.Errorf("secret [%s] in namespace [%s] was not recorded in the session", name, namespace)
+}
+
+func (r *sessionReplay) fetchReleaseNotes(version string) ([]byte, error) {
+	data, ok := r.session.ReleaseNotes[version]
+	if !ok {
+		return nil, fmt.Errorf("release notes for rancher [%s] were not recorded in the session", version)
+	}
+	return data, nil
+}
+
+// answerReader returns one recorded answer per Read, so a bufio.Reader only consumes an answer when a prompt asks
+// for it.
+type answerReader struct {
+	answers []string
+	pending string
+	out     io.Writer
+}
+
+func (a *answerReader) Read(p []byte) (int, error) {
+	if a.pending == "" {
+		if len(a.answers) == 0 {
+			return 0, io.EOF
+		}
+		a.pending = a.answers[0]
+		a.answers = a.answers[1:]
+		fmt.Fprint(a.out, a.pending)
+	}
+	n := copy(p, a.pending)
+	a.pending = a.pending[n:]
+	return n, nil
+}
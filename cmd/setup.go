@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/enescakir/emoji"
+	"github.com/fatih/color"
+	"github.com/rmweir/rancher-upgrader/internal/helm"
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
+	"github.com/urfave/cli/v2"
+)
+
+type SetupActionClient struct{}
+
+func SetupCommand() *cli.Command {
+	flags := append(clusterFlags(), &cli.BoolFlag{
+		Name:    "yes",
+		Aliases: []string{"y"},
+		Usage:   "Fix every missing prerequisite without asking",
+	})
+
+	c := &SetupActionClient{}
+	return &cli.Command{
+		Name:   "setup",
+		Usage:  "Check the prerequisites of the upgrade and offer to fix the missing ones, for a first run",
+		Action: c.Setup,
+		Flags:  flags,
+	}
+}
+
+// Setup walks through the prerequisites in the order the upgrade needs them: the helm repositories file, the
+// rancher-stable repo in it and a kubeconfig reaching the cluster. Nothing is changed without the user agreeing.
+func (s *SetupActionClient) Setup(ctx *cli.Context) error {
+	if ctx.String("kubeconfig") == "-" {
+		return fmt.Errorf("--kubeconfig - cannot be used with setup, stdin is needed to answer its prompts")
+	}
+	reader := newPrompter(os.Stdin, ctx.Bool("yes"))
+
+	fmt.Println(i18n.T(i18n.SetupHeader, emoji.Toolbox))
+	unresolved := 0
+
+	repositoryConfig, exists, err := helm.RepositoryConfig()
+	if err != nil {
+		return err
+	}
+	if exists {
+		color.Green("%s", i18n.T(i18n.CheckPassed, emoji.CheckMarkButton, i18n.T(i18n.SetupRepositoryConfig), repositoryConfig))
+	} else {
+		color.Yellow("%s", i18n.T(i18n.NoRepositoryConfig, emoji.Warning, repositoryConfig))
+		fmt.Print(i18n.T(i18n.OfferCreateRepositoryConfig))
+		create, err := promptForContinue(reader)
+		if err != nil {
+			return err
+		}
+		if create {
+			if err := helm.CreateRepositoryConfig(); err != nil {
+				return err
+			}
+			color.Green("%s", i18n.T(i18n.CheckPassed, emoji.CheckMarkButton, i18n.T(i18n.SetupRepositoryConfig), repositoryConfig))
+		} else {
+			unresolved++
+		}
+	}
+
+	repoName, err := helm.FindRancherStableRepo()
+	var missingRepo *helm.MissingRepoError
+	switch {
+	case errors.As(err, &missingRepo):
+		added, addErr := offerToAddRancherStableRepo(missingRepo, reader)
+		if addErr != nil || !added {
+			unresolved++
+		}
+	case err != nil:
+		return err
+	default:
+		color.Green("%s", i18n.T(i18n.CheckPassed, emoji.CheckMarkButton, i18n.T(i18n.CheckRepo), repoName))
+	}
+
+	if ctx.String("kubeconfig") == "" && ctx.String("kubeconfig-data") == "" {
+		unresolved++
+		color.Yellow("%s", i18n.T(i18n.CheckSkipped, emoji.WhiteQuestionMark, i18n.T(i18n.CheckCluster), i18n.T(i18n.NoKubeconfigPassed)))
+	} else if serverVersion, err := checkClusterConnection(ctx); err != nil {
+		unresolved++
+		color.Red("%s", i18n.T(i18n.CheckFailed, emoji.CrossMark, i18n.T(i18n.CheckCluster), err))
+	} else {
+		color.Green("%s", i18n.T(i18n.CheckPassed, emoji.CheckMarkButton, i18n.T(i18n.CheckCluster), serverVersion))
+	}
+
+	if unresolved != 0 {
+		return cli.Exit(i18n.T(i18n.SetupIncomplete, emoji.StopSign, unresolved), 1)
+	}
+	fmt.Println(i18n.T(i18n.SetupComplete, emoji.PartyingFace))
+	return nil
+}
+
+// checkClusterConnection returns the kubernetes version of the cluster the kubeconfig flags point to.
+func checkClusterConnection(ctx *cli.Context) (string, error) {
+	kubeconfigPath, cleanup, err := resolveKubeconfig(ctx, os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	opts := clientOptions(ctx)
+	opts.KubeconfigPath = kubeconfigPath
+	client, err := helm.NewReleaseClient(opts)
+	if err != nil {
+		return "", err
+	}
+	if err := client.VerifyKubeconfig(); err != nil {
+		return "", err
+	}
+	return client.ServerVersion()
+}
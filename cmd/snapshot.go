@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/rmweir/rancher-upgrader/internal/diff"
+	"helm.sh/helm/v3/pkg/release"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// snapshotRelease writes the manifest of targetRelease and the live state of its Deployments and of the ConfigMaps
+// they use to a directory under dir named after the release revision, and returns it. Secret data in the manifest is
+// masked and ConfigMap keys matching redactKeys are redacted. It is a record to compare against, not a backup.
+func (u *UpgradeActionClient) snapshotRelease(dir string, targetRelease *release.Release, redactKeys []string) (string, error) {
+	resources, err := diff.ParseManifest(targetRelease.Manifest)
+	if err != nil {
+		return "", err
+	}
+	sorted := make([]diff.Resource, 0, len(resources))
+	for resource := range resources {
+		sorted = append(sorted, resource)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].String() < sorted[j].String() })
+
+	snapshotDir := filepath.Join(dir, fmt.Sprintf("%s-%s-revision-%d", targetRelease.Namespace, targetRelease.Name, targetRelease.Version))
+	if err := os.MkdirAll(snapshotDir, 0700); err != nil {
+		return "", err
+	}
+
+	var manifest []string
+	for _, resource := range sorted {
+		data, err := yaml.Marshal(maskSecretDocument(resource, resources[resource]))
+		if err != nil {
+			return "", err
+		}
+		manifest = append(manifest, string(data))
+	}
+	if err := writeSnapshotFile(snapshotDir, "manifest.yaml", strings.Join(manifest, "---\n")); err != nil {
+		return "", err
+	}
+
+	configMaps := map[string]bool{}
+	for _, resource := range sorted {
+		if resource.Kind != "Deployment" {
+			continue
+		}
+		namespace := resource.Namespace
+		if namespace == "" {
+			namespace = targetRelease.Namespace
+		}
+		deployment, err := u.helmExecer.Deployment(namespace, resource.Name)
+		if err != nil {
+			return "", err
+		}
+		deployment.ManagedFields = nil
+		deployment.APIVersion, deployment.Kind = "apps/v1", "Deployment"
+		if err := writeSnapshotObject(snapshotDir, "deployment-"+deployment.Name+".yaml", deployment); err != nil {
+			return "", err
+		}
+		for _, name := range deploymentConfigMaps(deployment) {
+			configMaps[namespace+"/"+name] = true
+		}
+	}
+
+	names := make([]string, 0, len(configMaps))
+	for name := range configMaps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, namespacedName := range names {
+		namespace, name, _ := strings.Cut(namespacedName, "/")
+		configMap, err := u.helmExecer.ConfigMap(namespace, name)
+		// optional references may point to ConfigMaps that do not exist
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		configMap.ManagedFields = nil
+		configMap.APIVersion, configMap.Kind = "v1", "ConfigMap"
+		configMap.Data = redactConfigMapData(configMap.Data, redactKeys)
+		if err := writeSnapshotObject(snapshotDir, "configmap-"+configMap.Name+".yaml", configMap); err != nil {
+			return "", err
+		}
+	}
+	return snapshotDir, nil
+}
+
+// deploymentConfigMaps returns the names of the ConfigMaps the pods of deployment mount or take environment variables
+// from.
+func deploymentConfigMaps(deployment *appsv1.Deployment) []string {
+	var names []string
+	podSpec := deployment.Spec.Template.Spec
+	for _, volume := range podSpec.Volumes {
+		if volume.ConfigMap != nil {
+			names = append(names, volume.ConfigMap.Name)
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil {
+					names = append(names, source.ConfigMap.Name)
+				}
+			}
+		}
+	}
+	for _, container := range append(append([]corev1.Container(nil), podSpec.InitContainers...), podSpec.Containers...) {
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.ConfigMapRef != nil {
+				names = append(names, envFrom.ConfigMapRef.Name)
+			}
+		}
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.ConfigMapKeyRef != nil {
+				names = append(names, env.ValueFrom.ConfigMapKeyRef.Name)
+			}
+		}
+	}
+	return names
+}
+
+// redactConfigMapData redacts the entries of data whose keys match redactKeys.
+func redactConfigMapData(data map[string]string, redactKeys []string) map[string]string {
+	values := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		values[key] = value
+	}
+	redacted := make(map[string]string, len(data))
+	for key, value := range redactValues(values, redactKeys) {
+		redacted[key] = fmt.Sprint(value)
+	}
+	return redacted
+}
+
+func writeSnapshotObject(dir, name string, object interface{}) error {
+	data, err := yaml.Marshal(object)
+	if err != nil {
+		return err
+	}
+	return writeSnapshotFile(dir, name, string(data))
+}
+
+func writeSnapshotFile(dir, name, content string) error {
+	return os.WriteFile(filepath.Join(dir, name), []byte(content), 0600)
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/enescakir/emoji"
+	"github.com/fatih/color"
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
+	"github.com/rmweir/rancher-upgrader/internal/support"
+)
+
+// checkSupport warns when the installed version or the target version is past the end of life of its minor line.
+// With enforce set, an end of life target is an error instead of a warning. Both are checked as of now.
+func checkSupport(matrix support.Matrix, currentVersion, targetVersion string, enforce bool, now time.Time) error {
+	currentEndOfLife, ok, err := matrix.EndOfLife(currentVersion)
+	if err != nil {
+		return err
+	}
+	if ok && now.After(currentEndOfLife) {
+		color.Yellow("%s", i18n.T(i18n.CurrentVersionEndOfLife, emoji.Warning, currentVersion, currentEndOfLife.Format("2006-01-02")))
+	}
+
+	targetEndOfLife, ok, err := matrix.EndOfLife(targetVersion)
+	if err != nil {
+		return err
+	}
+	if !ok || !now.After(targetEndOfLife) {
+		return nil
+	}
+	if enforce {
+		return fmt.Errorf("target version [%s] reached its end of life on %s, pick a supported target or re-run without --enforce-support",
+			targetVersion, targetEndOfLife.Format("2006-01-02"))
+	}
+	color.Yellow("%s", i18n.T(i18n.TargetVersionEndOfLife, emoji.Warning, targetVersion, targetEndOfLife.Format("2006-01-02")))
+	return nil
+}
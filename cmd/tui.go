@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/enescakir/emoji"
+	"github.com/fatih/color"
+	"github.com/rmweir/rancher-upgrader/internal/cleanup"
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
+	"golang.org/x/term"
+)
+
+// tuiItem is a line of the notes shown by the TUI. Known issues and breaking behavior changes must be ticked before
+// the review can be confirmed.
+type tuiItem struct {
+	text  string
+	color *color.Color
+	// version is the release the item belongs to, acknowledgement the text recorded once it is ticked
+	version         string
+	acknowledgement string
+	mustAck         bool
+	acked           bool
+}
+
+// canUseTUI reports whether stdin and stdout are both terminals, which the TUI needs to read key presses and redraw.
+func canUseTUI() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// walkthroughTUI shows the same notes as walkthroughRelevantNotes in a single scrollable screen. Every known issue and
+// breaking behavior change is ticked instead of prompted for, and the review is confirmed once all of them are. It
+// returns the same values as walkthroughRelevantNotes. Key presses are read from stdin directly rather than through
+// reader, bubbletea only switches a terminal to raw mode.
+func walkthroughTUI(notes []releaseNotes, sections []noteSection, since time.Time, breakingKeywords []string, skipEmpty bool, reader *prompter, events *eventStream) (bool, []string, error) {
+	model := newTUIModel(tuiItems(notes, sections, since, breakingKeywords, skipEmpty))
+	// signals are left to the cleanup handler, which has the program restore the terminal before exiting
+	program := tea.NewProgram(model, tea.WithAltScreen(), tea.WithoutSignalHandler())
+	finished := make(chan struct{})
+	defer cleanup.Register(func() {
+		program.Kill()
+		<-finished
+	})()
+
+	final, err := program.Run()
+	close(finished)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to run the --tui review: %w", err)
+	}
+	model = final.(tuiModel)
+	if !model.confirmed {
+		return false, nil, nil
+	}
+	return true, tuiAcknowledged(model.items, notes, events), nil
+}
+
+// tuiModel is the bubbletea model of the review: the notes, which one the cursor is on and the first one shown.
+type tuiModel struct {
+	items         []tuiItem
+	cursor        int
+	offset        int
+	width, height int
+	status        string
+	confirmed     bool
+}
+
+func newTUIModel(items []tuiItem) tuiModel {
+	// the size of the terminal replaces this once it is known
+	return tuiModel{items: items, width: 80, height: 24, status: i18n.T(i18n.TUIHelp)}
+}
+
+func (m tuiModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case tea.KeyMsg:
+		m.status = i18n.T(i18n.TUIHelp)
+		pageSize := m.rows()
+		last := len(m.items) - 1
+		switch msg.String() {
+		case "up", "k":
+			m.cursor = clampInt(m.cursor-1, 0, last)
+		case "down", "j":
+			m.cursor = clampInt(m.cursor+1, 0, last)
+		case "pgup":
+			m.cursor = clampInt(m.cursor-pageSize, 0, last)
+		case "pgdown":
+			m.cursor = clampInt(m.cursor+pageSize, 0, last)
+		case " ":
+			if m.items[m.cursor].mustAck {
+				m.items[m.cursor].acked = !m.items[m.cursor].acked
+			}
+		case "enter":
+			if pending := pendingAcknowledgements(m.items); pending != 0 {
+				m.status = i18n.T(i18n.TUIPendingAcknowledgements, pending)
+				break
+			}
+			m.confirmed = true
+			return m, tea.Quit
+		case "q", "ctrl+c":
+			return m, tea.Quit
+		}
+	}
+	m.offset = scrollOffset(m.lines(), m.cursor, m.offset, m.rows())
+	return m, nil
+}
+
+// View shows the items from offset on as far as they fit, with the status line at the bottom.
+func (m tuiModel) View() string {
+	lines := m.lines()
+	rows := m.rows()
+	var screen strings.Builder
+	used := 0
+	for index := m.offset; index < len(m.items) && used < rows; index++ {
+		for _, line := range lines[index] {
+			if used == rows {
+				break
+			}
+			if m.items[index].color != nil {
+				line = m.items[index].color.Sprint(line)
+			}
+			screen.WriteString(line + "\n")
+			used++
+		}
+	}
+	screen.WriteString(strings.Repeat("\n", rows-used+1) + m.status)
+	return screen.String()
+}
+
+// rows is how many lines of items fit above the status line.
+func (m tuiModel) rows() int {
+	if m.height < 3 {
+		return 1
+	}
+	return m.height - 2
+}
+
+// lines wraps every item to the width of the screen, marking the item at the cursor and the tick boxes.
+func (m tuiModel) lines() [][]string {
+	lines := make([][]string, len(m.items))
+	for index, item := range m.items {
+		prefix := "  "
+		if index == m.cursor {
+			prefix = "> "
+		}
+		if item.mustAck {
+			if item.acked {
+				prefix += "[x] "
+			} else {
+				prefix += "[ ] "
+			}
+		}
+		lines[index] = wrapRunes(prefix, item.text, m.width)
+	}
+	return lines
+}
+
+// scrollOffset returns the index of the first item to show so that the item at cursor is visible, scrolling no more
+// than needed from offset.
+func scrollOffset(lines [][]string, cursor, offset, rows int) int {
+	if cursor < offset {
+		return cursor
+	}
+	for offset < cursor {
+		used := 0
+		for index := offset; index <= cursor; index++ {
+			used += len(lines[index])
+		}
+		if used <= rows {
+			break
+		}
+		offset++
+	}
+	return offset
+}
+
+// tuiItems lays out the notes of every release after the installed one, the way walkthroughRelevantNotes prints them.
+func tuiItems(notes []releaseNotes, sections []noteSection, since time.Time, breakingKeywords []string, skipEmpty bool) []tuiItem {
+	items := []tuiItem{{text: i18n.T(i18n.ReleaseCount, len(notes)-1, notes[0].version, notes[len(notes)-1].version)}}
+	var skipped []string
+	for index, next := range notes[1:] {
+		if skipEmpty && isEmptyRelease(next, sections, since) {
+			skipped = append(skipped, next.version)
+			continue
+		}
+		items = append(items, tuiItem{}, tuiItem{text: fmt.Sprintf("%s -> %s (%s)", notes[index].version, next.version, formatPublishedAt(next.publishedAt))})
+		items = append(items, tuiItem{text: next.url})
+		if next.draft {
+			items = append(items, tuiItem{text: i18n.T(i18n.DraftReleaseNotes, emoji.Warning, next.version), color: color.New(color.FgYellow)})
+		} else if next.prerelease {
+			items = append(items, tuiItem{text: i18n.T(i18n.PrereleaseNotes, emoji.Warning, next.version), color: color.New(color.FgYellow)})
+		}
+		if next.fetchErr != nil {
+			items = append(items, tuiItem{text: i18n.T(i18n.NotesNotFetched, emoji.CrossMark, next.version, next.url), color: color.New(color.FgRed)})
+			continue
+		}
+		if !since.IsZero() && !next.publishedAt.IsZero() && next.publishedAt.Before(since) {
+			items = append(items, tuiItem{text: i18n.T(i18n.NotesCollapsedBeforeSince, next.version, since.Format(sinceLayout))})
+			continue
+		}
+		for _, section := range sections {
+			var bullets []string
+			for _, bullet := range next.sections[section.name] {
+				if bullet = strings.TrimSpace(bullet); bullet != "" && bullet != "-->" {
+					bullets = append(bullets, bullet)
+				}
+			}
+			if len(bullets) == 0 {
+				continue
+			}
+			items = append(items, tuiItem{text: i18n.T(section.label) + ":", color: section.color})
+			for _, bullet := range bullets {
+				item := tuiItem{text: bullet, color: section.color, version: next.version}
+				switch {
+				case section.name == sectionKnownIssues:
+					item.mustAck = true
+					item.acknowledgement = bullet
+					if reference := issueReference(bullet); reference != "" {
+						item.acknowledgement = fmt.Sprintf("[#%s] %s", reference, bullet)
+					}
+				case section.name == sectionBehaviorChanges && matchingKeyword(bullet, breakingKeywords) != "":
+					item.mustAck = true
+				}
+				items = append(items, item)
+			}
+		}
+	}
+	if len(skipped) != 0 {
+		items = append(items, tuiItem{}, tuiItem{text: i18n.T(i18n.SkippedEmptyReleases, len(skipped), strings.Join(skipped, ", "))})
+	}
+	return items
+}
+
+// pendingAcknowledgements counts the items that still have to be ticked.
+func pendingAcknowledgements(items []tuiItem) int {
+	pending := 0
+	for _, item := range items {
+		if item.mustAck && !item.acked {
+			pending++
+		}
+	}
+	return pending
+}
+
+// tuiAcknowledged returns the acknowledged known issues and emits the events walkthroughRelevantNotes would have.
+func tuiAcknowledged(items []tuiItem, notes []releaseNotes, events *eventStream) []string {
+	var acknowledged []string
+	for _, item := range items {
+		if !item.acked || item.acknowledgement == "" {
+			continue
+		}
+		acknowledged = append(acknowledged, item.acknowledgement)
+		events.emit(event{Event: eventKnownIssueAcknowledged, Version: item.version, Issue: item.acknowledgement})
+	}
+	for _, release := range notes[1:] {
+		if release.fetchErr == nil {
+			events.emit(event{Event: eventReleaseReviewed, Version: release.version})
+		}
+	}
+	return acknowledged
+}
+
+// wrapRunes splits prefix followed by text into lines of at most width runes, the following lines indented as far as
+// the prefix.
+func wrapRunes(prefix, text string, width int) []string {
+	indent := strings.Repeat(" ", len([]rune(prefix)))
+	available := width - len([]rune(prefix))
+	runes := []rune(text)
+	if available <= 0 || len(runes) <= available {
+		return []string{prefix + text}
+	}
+	var lines []string
+	for len(runes) > available {
+		lines = append(lines, prefix+string(runes[:available]))
+		runes = runes[available:]
+		prefix = indent
+	}
+	return append(lines, prefix+string(runes))
+}
+
+func clampInt(value, low, high int) int {
+	if value < low {
+		return low
+	}
+	if value > high {
+		return high
+	}
+	return value
+}
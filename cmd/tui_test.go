@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
+)
+
+func TestTUIModel(t *testing.T) {
+	items := []tuiItem{
+		{text: "Known Issues:"},
+		{text: "first issue", mustAck: true, acknowledgement: "first issue"},
+		{text: "second issue", mustAck: true, acknowledgement: "second issue"},
+	}
+	press := func(m tuiModel, keys ...tea.KeyMsg) (tuiModel, tea.Cmd) {
+		var cmd tea.Cmd
+		for _, key := range keys {
+			var updated tea.Model
+			updated, cmd = m.Update(key)
+			m = updated.(tuiModel)
+		}
+		return m, cmd
+	}
+	down := tea.KeyMsg{Type: tea.KeyDown}
+	space := tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+	enter := tea.KeyMsg{Type: tea.KeyEnter}
+
+	m, cmd := press(newTUIModel(items), down, space, enter)
+	if m.confirmed || cmd != nil {
+		t.Fatal("review confirmed with a known issue left unticked")
+	}
+	if want := i18n.T(i18n.TUIPendingAcknowledgements, 1); m.status != want {
+		t.Errorf("status = %q, want %q", m.status, want)
+	}
+
+	m, cmd = press(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}}, space, enter)
+	if !m.confirmed || cmd == nil {
+		t.Fatal("review not confirmed once every known issue is ticked")
+	}
+	if !m.items[1].acked || !m.items[2].acked {
+		t.Errorf("ticked items = %t, %t, want both", m.items[1].acked, m.items[2].acked)
+	}
+
+	// lines that are not acknowledged cannot be ticked, and quitting does not confirm
+	m, cmd = press(newTUIModel(items), space, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}})
+	if m.items[0].acked || m.confirmed || cmd == nil {
+		t.Errorf("after ticking a header and quitting: acked = %t, confirmed = %t, quit = %t", m.items[0].acked, m.confirmed, cmd != nil)
+	}
+}
+
+func TestScrollOffset(t *testing.T) {
+	// the second item wraps over two lines
+	lines := [][]string{{"a"}, {"b", "b"}, {"c"}, {"d"}}
+	tests := []struct {
+		name   string
+		cursor int
+		offset int
+		want   int
+	}{
+		{name: "cursor visible", cursor: 1, offset: 0, want: 0},
+		{name: "cursor below", cursor: 3, offset: 0, want: 2},
+		{name: "cursor above", cursor: 0, offset: 2, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scrollOffset(lines, tt.cursor, tt.offset, 3); got != tt.want {
+				t.Errorf("scrollOffset(cursor %d, offset %d) = %d, want %d", tt.cursor, tt.offset, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,167 +1,1681 @@
 package cmd
 
 import (
-	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"regexp"
+	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/blang/semver/v4"
 	"github.com/enescakir/emoji"
 	"github.com/fatih/color"
 	"github.com/ghodss/yaml"
+	"github.com/google/uuid"
+	"github.com/rmweir/rancher-upgrader/internal/clock"
 	"github.com/rmweir/rancher-upgrader/internal/helm"
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
+	"github.com/rmweir/rancher-upgrader/internal/proxy"
+	"github.com/rmweir/rancher-upgrader/internal/support"
+	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
 	"helm.sh/helm/v3/pkg/repo"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 )
 
-const (
-	ghReleaseNotesAPIPrefix      = "https://api.github.com/repos/rancher/rancher/releases/tags/"
-	rancherReleaseNotesPrefix    = "https://github.com/rancher/rancher/releases/tag/"
-	majorBugFixHeader            = "# Major Bug Fixes"
-	rancherBehaviorChangesHeader = "# Rancher Behavior Changes"
-	knownIssuesHeader            = "# Known Issues"
-	installUpgradeNotesHeader    = "# Install/Upgrade Notes"
-)
+// exitCodeKnownIssueGate is the exit code when a known issue matches --fail-on-known-issue, so CI can tell a policy
+// stop apart from a failure.
+const exitCodeKnownIssueGate = 3
 
-var (
-	markdownCommentsReg = regexp.MustCompile("<!--[A-Za-z0-9-#/, ]*-->")
-)
+// exitCodeIncompleteNotes is the exit code of a run that finished while the notes of some releases could not be
+// fetched, so CI can tell that they were never reviewed.
+const exitCodeIncompleteNotes = 4
+
+// exitCodeBehaviorAckGate is the exit code of an unattended run stopped by behavior changes that --acked-behavior-changes
+// does not acknowledge.
+const exitCodeBehaviorAckGate = 5
+
+// incompleteNotesError is returned by a run that finished without the notes of versions.
+type incompleteNotesError struct {
+	versions []string
+}
+
+func (e incompleteNotesError) Error() string {
+	return fmt.Sprintf("the notes of %s could not be fetched and were not reviewed", strings.Join(e.versions, ", "))
+}
+
+func (e incompleteNotesError) ExitCode() int {
+	return exitCodeIncompleteNotes
+}
+
+type helmExecer interface {
+	ListReleases() ([]*release.Release, error)
+	FindRancherReleases() ([]*release.Release, error)
+	History(namespace, releaseName string) ([]*release.Release, error)
+	GetNextSupportedRancherChartVersion(currentVersion string) (string, error)
+	GetUpgradePath(currentVersion string) ([]string, error)
+	GetRancherChartForVersion(version string) (*repo.ChartVersion, error)
+	GetRancherChartForAppVersion(appVersion string) (*repo.ChartVersion, error)
+	LoadRancherChart(version string) (*chart.Chart, error)
+	Upgrade(release *release.Release, targetChart *chart.Chart, overrideValues map[string]interface{}, opts helm.UpgradeOptions) (*release.Release, error)
+	Rollback(namespace, releaseName string, revision int) error
+	Render(release *release.Release, targetChart *chart.Chart, overrideValues map[string]interface{}) (string, error)
+	Deployment(namespace, name string) (*appsv1.Deployment, error)
+	ConfigMap(namespace, name string) (*corev1.ConfigMap, error)
+	Secret(namespace, name string) (*corev1.Secret, error)
+}
+
+type UpgradeActionClient struct {
+	helmExecer helmExecer
+	notes      releaseNotesSource
+	audit      auditEntry
+	explain    bool
+	// tui reviews the notes in the TUI instead of prompting for each section
+	tui bool
+	// quiet walks through the notes without printing them
+	quiet bool
+	// unattended runs never prompt, --require-behavior-ack gates them on the acknowledged behavior changes
+	unattended bool
+	support    support.Matrix
+	events     *eventStream
+	report     upgradeReport
+	// unfetched are the versions whose notes could not be fetched
+	unfetched []string
+	clock     clock.Clock
+	// runID correlates the output, audit entry, report, events and release dump of this run
+	runID string
+	// clusterValues are the override values read from --values-from-configmap and --values-from-secret, merged over
+	// the override values of the release
+	clusterValues map[string]interface{}
+}
+
+func UpgradeCommand() *cli.Command {
+	flags := append(append(clusterFlags(), repoFlags()...),
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Render the upgrade and show the resulting manifest changes without applying them, pass --dry-run=false to upgrade",
+			Value: true,
+		},
+		&cli.BoolFlag{
+			Name:    "yes",
+			Aliases: []string{"y"},
+			Usage:   "Answer yes to every confirmation and keep the current override values, for unattended runs",
+		},
+		&cli.BoolFlag{
+			Name:  "interactive",
+			Usage: "Set to false to never prompt, same as --yes --quiet: every confirmation is answered yes, the current override values are kept without asking and the release notes are not printed. Refuses flags that need typed answers",
+			Value: true,
+		},
+		&cli.BoolFlag{
+			Name:  "quiet",
+			Usage: "Do not print the release notes walkthrough and impact summary, only how many releases were reviewed and known issues acknowledged. The --fail-on-known-issue and --require-behavior-ack gates still apply. Needs --yes",
+		},
+		&cli.BoolFlag{
+			Name:  "output-current-values-only",
+			Usage: "Print the current override values of the detected release to stdout and exit without upgrading",
+		},
+		&cli.StringFlag{
+			Name:  "output",
+			Usage: "Output format of --output-current-values-only, one of: yaml, json",
+			Value: "yaml",
+		},
+		&cli.BoolFlag{
+			Name:  "since-last-upgrade",
+			Usage: "Review the notes of every release since the chart version the release was last upgraded from, found in the helm history, up to the installed version, and exit without upgrading",
+		},
+		&cli.BoolFlag{
+			Name:  "list-known-issues-only",
+			Usage: "Print the deduplicated known issues of the releases after --from up to --to and exit without upgrading. --from defaults to the installed version and --to to the next supported version, the cluster is not used when both are set",
+		},
+		&cli.StringFlag{
+			Name:  "from",
+			Usage: "Rancher chart version the known issues of --list-known-issues-only are listed from",
+		},
+		&cli.StringFlag{
+			Name:  "to",
+			Usage: "Rancher chart version the known issues of --list-known-issues-only are listed up to",
+		},
+		&cli.StringFlag{
+			Name:  "known-issues-format",
+			Usage: "Output format of --list-known-issues-only, one of: text, json",
+			Value: knownIssuesFormatText,
+		},
+		&cli.BoolFlag{
+			Name:  "ignore-notes-errors",
+			Usage: "Accept that the notes of some releases could not be fetched: they are still listed as unavailable, but the run exits with code 0 instead of 4",
+		},
+		&cli.BoolFlag{
+			Name:    "skip-notes",
+			Aliases: []string{"skip-to-values"},
+			Usage:   "Skip fetching and reviewing the release notes and go straight to the values, for re-runs after the notes were reviewed",
+		},
+		&cli.BoolFlag{
+			Name:  "tui",
+			Usage: "Review the release notes in a scrollable screen, ticking the known issues and breaking changes, instead of prompting for each section. Needs a terminal, the prompts are used otherwise",
+		},
+		&cli.BoolFlag{
+			Name:  "explain",
+			Usage: "Explain what each step of the upgrade does and why before doing it",
+		},
+		&cli.BoolFlag{
+			Name:  "latest",
+			Usage: "Upgrade to the newest supported version, one supported upgrade at a time",
+		},
+		&cli.StringFlag{
+			Name:  "chart-path",
+			Usage: "Upgrade to the rancher chart directory or .tgz archive at this path instead of a version from the chart repository",
+			Value: "",
+		},
+		&cli.BoolFlag{
+			Name:  "stay-on-minor",
+			Usage: "Only upgrade to newer patches of the installed minor version, never to the next minor version",
+		},
+		&cli.BoolFlag{
+			Name:  "enforce-webhook-compat",
+			Usage: "Refuse to upgrade when the installed rancher-webhook works with neither the installed nor the target rancher version",
+		},
+		&cli.BoolFlag{
+			Name:  "allow-app-version-downgrade",
+			Usage: "Upgrade to a chart shipping an older rancher appVersion than the installed chart without asking. Unattended runs refuse such upgrades otherwise",
+		},
+		upgradePolicyFlag(),
+		&cli.StringFlag{
+			Name:  "target-app-version",
+			Usage: "Upgrade to the chart version shipping this rancher version, e.g. v2.7.9, one supported upgrade at a time",
+			Value: "",
+		},
+		&cli.StringFlag{
+			Name:  "target-version-file",
+			Usage: "Read the rancher version to upgrade to, as --target-app-version takes it, from this file, so a pipeline only bumps the file to trigger an upgrade. Pass --yes for unattended runs",
+		},
+		&cli.StringSliceFlag{
+			Name:  "sections",
+			Usage: "Comma separated release notes sections to review, any of: " + strings.Join(sectionNames(), ", "),
+			Value: cli.NewStringSlice(sectionNames()...),
+		},
+		&cli.StringFlag{
+			Name:  "since",
+			Usage: "Only show the notes of releases published on or after this date, in YYYY-MM-DD form",
+			Value: "",
+		},
+		&cli.StringFlag{
+			Name:  "fail-on-known-issue",
+			Usage: fmt.Sprintf("Regular expression, stop with exit code %d if any known issue in the upgraded range matches it", exitCodeKnownIssueGate),
+			Value: "",
+		},
+		&cli.BoolFlag{
+			Name:  "skip-empty-releases",
+			Usage: "Do not stop at releases whose selected sections have nothing left after deduplication, list them once after the walkthrough instead",
+		},
+		&cli.BoolFlag{
+			Name:  "require-behavior-ack",
+			Usage: fmt.Sprintf("In unattended runs, stop with exit code %d if a behavior change in the upgraded range is not listed in --acked-behavior-changes, so automated upgrades still need someone to review them", exitCodeBehaviorAckGate),
+		},
+		&cli.StringFlag{
+			Name:  "acked-behavior-changes",
+			Usage: "File listing the reviewed behavior changes for --require-behavior-ack, one per line as the release notes word them. Case, spacing and bullet markers are ignored, so are empty lines and lines starting with #",
+		},
+		&cli.StringSliceFlag{
+			Name:  "impact-keywords",
+			Usage: "Comma separated keywords that mark an install/upgrade note as disruptive in the estimated impact summary",
+			Value: cli.NewStringSlice(defaultImpactKeywords...),
+		},
+		&cli.StringSliceFlag{
+			Name:  "breaking-keywords",
+			Usage: "Comma separated phrases that mark a behavior change as breaking, breaking changes are acknowledged one by one",
+			Value: cli.NewStringSlice(defaultBreakingKeywords...),
+		},
+		&cli.StringFlag{
+			Name:  "support-matrix",
+			Usage: "Path to a JSON rancher support matrix to use instead of the bundled one",
+			Value: "",
+		},
+		&cli.BoolFlag{
+			Name:  "enforce-support",
+			Usage: "Refuse to upgrade to a version that is past its end of life instead of warning",
+		},
+		&cli.IntFlag{
+			Name:  "from-revision",
+			Usage: "Base the upgrade on the values and chart of this revision of the rancher release instead of the latest one",
+		},
+		redactKeysFlag(),
+		&cli.StringFlag{
+			Name:  "audit-log",
+			Usage: "Append a JSON line describing this run to the file at this path",
+			Value: "",
+		},
+		&cli.StringFlag{
+			Name:  "run-id",
+			Usage: "ID correlating the output, audit entry, report and events of this run, e.g. the ID of the CI job running it. Defaults to a random UUID",
+		},
+		&cli.StringFlag{
+			Name:  "events-stream",
+			Usage: "Write a JSON line for each step of the upgrade to this file or named pipe, - writes them to stdout and moves all other output to stderr",
+			Value: "",
+		},
+		&cli.BoolFlag{
+			Name:  "require-confirm-phrase",
+			Usage: "Require typing the release name, or --confirm-phrase, instead of y before the release is upgraded for real",
+		},
+		&cli.StringFlag{
+			Name:  "confirm-phrase",
+			Usage: "Phrase --require-confirm-phrase asks for, defaults to the release name",
+			Value: "",
+		},
+		&cli.StringFlag{
+			Name:  "report",
+			Usage: "Write the upgrade plan, with the version path, reviewed release notes and default values changes, to this file for change management",
+			Value: "",
+		},
+		&cli.StringFlag{
+			Name:  "report-format",
+			Usage: "Format of the --report file, one of: html, json",
+			Value: reportFormatHTML,
+		},
+		&cli.StringFlag{
+			Name:  "description",
+			Usage: "Description to record on the upgraded release revision, defaults to the tool name and version range",
+			Value: "",
+		},
+		&cli.StringSliceFlag{
+			Name:  "label",
+			Usage: "Label in key=value form to record on the upgraded release revision, can be repeated",
+		},
+		&cli.StringFlag{
+			Name:  "notes-cache-dir",
+			Usage: "Cache fetched release notes in this directory for a day, safe to share between concurrent runs",
+			Value: "",
+		},
+		&cli.IntFlag{
+			Name:  "concurrency",
+			Usage: "How many release notes are fetched at once. Higher values are faster but put more pressure on the GitHub API rate limit",
+			Value: defaultConcurrency,
+		},
+		&cli.StringFlag{
+			Name:  "record-session",
+			Usage: "Record the prompts, answers and fetched release notes of this run to the file at this path, values are redacted",
+			Value: "",
+		},
+		&cli.StringFlag{
+			Name:  "simulate",
+			Usage: "Replay a session recorded with --record-session from the file at this path without touching a cluster",
+			Value: "",
+		},
+		&cli.BoolFlag{
+			Name:  "atomic",
+			Usage: "Wait for the upgraded resources to be ready and roll the release back if they are not",
+		},
+		&cli.IntFlag{
+			Name:  "upgrade-retries",
+			Usage: "Retry an upgrade failing with a transient API server or webhook error this many times, requires --atomic",
+		},
+		&cli.DurationFlag{
+			Name:  "upgrade-retry-backoff",
+			Usage: "Wait before the first upgrade retry, doubled before each following retry",
+			Value: 10 * time.Second,
+		},
+		&cli.BoolFlag{
+			Name:  "lint",
+			Usage: "Render the target chart with the proposed values before upgrading and stop on rendering errors",
+		},
+		&cli.StringFlag{
+			Name:  "snapshot-dir",
+			Usage: "Before a real upgrade, write the release manifest and the live rancher Deployment and the ConfigMaps it uses to a directory under this one, to compare against after the upgrade. Secret data is masked and keys matching --redact-keys are redacted",
+		},
+		&cli.StringSliceFlag{
+			Name:  "values-from-configmap",
+			Usage: fmt.Sprintf("Use the values YAML in this ConfigMap, as namespace/name or namespace/name:key with key defaulting to %s, merged over the release's override values. Repeat to merge several in order, later ones win", defaultClusterValuesKey),
+		},
+		&cli.StringSliceFlag{
+			Name:  "values-from-secret",
+			Usage: "Same as --values-from-configmap for a Secret, merged after the ConfigMaps",
+		},
+		&cli.BoolFlag{
+			Name:  "guided-values",
+			Usage: "When the target chart ships a values.schema.json, offer to set the override values one by one, with the descriptions, defaults and types of the schema. Objects and arrays are still set with a values file. Cannot be used with --record-session",
+		},
+		&cli.StringFlag{
+			Name:   "dump-release-json",
+			Usage:  "Write the detected release, its info, chart metadata, config and manifest, as JSON to this file to attach to a bug report. Secret data is masked and keys matching --redact-keys are redacted, other config values may still be sensitive",
+			Hidden: true,
+		},
+		&cli.StringFlag{
+			Name:  "save-values",
+			Usage: "Write the override values applied by the upgrade to this file, as JSON for a .json file and as YAML otherwise, e.g. to commit them to a GitOps repo. The values are written as they are, keys matching --redact-keys are only masked when --redact-keys is passed",
+		},
+		&cli.StringFlag{
+			Name:  "post-upgrade-check",
+			Usage: "Shell command to run after the upgrade, the run fails and a rollback is offered if it exits non-zero",
+			Value: "",
+		},
+		&cli.StringFlag{
+			Name:  "diff-format",
+			Usage: "How the default values and dry-run manifest diffs are shown, one of: fields, unified, json",
+			Value: diffFormatFields,
+		},
+		&cli.IntFlag{
+			Name:  "diff-context",
+			Usage: "Number of unchanged lines shown around each change with --diff-format=unified",
+			Value: 3,
+		},
+		&cli.StringFlag{
+			Name:  "post-renderer",
+			Usage: "Path to an executable to use as a helm post-renderer for the upgraded manifests",
+			Value: "",
+		},
+	)
+	flags = append(flags, noteHeaderFlags()...)
+
+	c := &UpgradeActionClient{clock: clock.Real{}}
+	return &cli.Command{
+		Name:   "upgrade",
+		Usage:  "Bring the cluster up",
+		Action: c.UpgradeRancher,
+		Flags:  flags,
+	}
+}
+
+// clusterFlags are shared by every command that needs to locate the rancher release.
+func clusterFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "kubeconfig",
+			Usage:   "Specify kubeconfig path, - reads the kubeconfig from stdin which then can no longer answer prompts",
+			Value:   "",
+			EnvVars: []string{"KUBECONFIG"},
+		},
+		&cli.StringFlag{
+			Name:    "kubeconfig-data",
+			Usage:   "Base64 encoded kubeconfig content, used instead of --kubeconfig",
+			Value:   "",
+			EnvVars: []string{"KUBECONFIG_DATA"},
+		},
+		&cli.StringFlag{
+			Name:  "namespace",
+			Usage: "Only look for the rancher release in this namespace, avoids listing releases across all namespaces",
+			Value: "",
+		},
+		&cli.StringFlag{
+			Name:    "helm-driver",
+			Usage:   "Storage backend of the helm release data, one of: secret, configmap, memory, sql",
+			Value:   "secret",
+			EnvVars: []string{"HELM_DRIVER"},
+		},
+		&cli.StringFlag{
+			Name:  "release-name",
+			Usage: "Name of the rancher release to use when more than one is installed",
+			Value: "",
+		},
+		&cli.BoolFlag{
+			Name:  "all-namespaces",
+			Usage: "Look for the rancher release in all namespaces when it is not in cattle-system, which requires listing secrets cluster-wide. Set to false to only look in cattle-system",
+			Value: true,
+		},
+		&cli.BoolFlag{
+			Name:  "scan-all-namespaces",
+			Usage: "Always look for rancher releases in all namespaces, not only when none is in cattle-system, to find duplicate releases elsewhere. Slow in large clusters, releases in cattle-system are offered first",
+		},
+	}
+}
+
+// noteHeaderFlags override the release notes headers, for distributions whose notes are laid out like rancher's but
+// titled differently.
+func noteHeaderFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "bugfix-header",
+			Usage: "Release notes header starting the bugfixes section",
+			Value: majorBugFixHeader,
+		},
+		&cli.StringFlag{
+			Name:  "behavior-changes-header",
+			Usage: "Release notes header starting the behavior changes section",
+			Value: rancherBehaviorChangesHeader,
+		},
+		&cli.StringFlag{
+			Name:  "known-issues-header",
+			Usage: "Release notes header starting the known issues section",
+			Value: knownIssuesHeader,
+		},
+		&cli.StringFlag{
+			Name:  "install-notes-header",
+			Usage: "Release notes header starting the install/upgrade notes section",
+			Value: installUpgradeNotesHeader,
+		},
+		&cli.StringFlag{
+			Name:  "versions-header",
+			Usage: "Release notes header following the install/upgrade notes section",
+			Value: versionsHeader,
+		},
+	}
+}
+
+func upgradePolicyFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "upgrade-policy",
+		Usage: "Which newer version to upgrade to, one of: " + helm.UpgradePolicyNames(),
+		Value: string(helm.PolicyPatchFirst),
+	}
+}
+
+// repoFlags are shared by every command that needs to look up or load rancher charts.
+func repoFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "oci-repo",
+			Usage: "OCI reference to the rancher chart, e.g. oci://registry.example.com/rancher/rancher, used instead of the rancher-stable repo",
+			Value: "",
+		},
+		&cli.DurationFlag{
+			Name:  "repo-timeout",
+			Usage: "How long to wait for the rancher-stable repo index to update and load, 0 waits forever",
+			Value: 2 * time.Minute,
+		},
+		&cli.BoolFlag{
+			Name:  "skip-repo-update",
+			Usage: "Use the cached rancher-stable repo index instead of updating it",
+		},
+		&cli.BoolFlag{
+			Name:  "verify",
+			Usage: "Verify the provenance file and signature of every downloaded rancher chart, refusing charts that are unsigned or fail verification",
+		},
+		&cli.StringFlag{
+			Name:  "keyring",
+			Usage: "GPG keyring holding the keys --verify accepts signatures from",
+			Value: defaultKeyring(),
+		},
+		&cli.StringFlag{
+			Name:    "repo-username",
+			Usage:   "Username for a rancher-stable repo requiring basic auth, used when the helm repositories file holds no credentials for it",
+			EnvVars: []string{"REPO_USERNAME"},
+		},
+		&cli.StringFlag{
+			Name:    "repo-password",
+			Usage:   "Password for --repo-username",
+			EnvVars: []string{"REPO_PASSWORD"},
+		},
+		&cli.StringFlag{
+			Name:  "proxy",
+			Usage: "Proxy for the chart repository and GitHub requests, e.g. http://proxy.example.com:3128, overriding HTTP_PROXY and HTTPS_PROXY",
+		},
+		&cli.StringFlag{
+			Name:  "no-proxy",
+			Usage: "Comma separated hosts, domains and CIDRs to reach without the proxy, overriding NO_PROXY",
+		},
+		&cli.BoolFlag{
+			Name:  "search-all-repos",
+			Usage: "When a rancher version is not in the rancher-stable repo, look for it in the cached indexes of the other configured helm repos and report which ones serve it",
+		},
+		&cli.DurationFlag{
+			Name:  "max-index-age",
+			Usage: "Warn when the rancher-stable repo index was generated longer ago than this, 0 disables the warning",
+			Value: 7 * 24 * time.Hour,
+		},
+	}
+}
+
+// defaultKeyring is the GPG public keyring helm verifies charts with by default.
+func defaultKeyring() string {
+	if home := os.Getenv("GNUPGHOME"); home != "" {
+		return filepath.Join(home, "pubring.gpg")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".gnupg", "pubring.gpg")
+}
+
+func clientOptions(ctx *cli.Context) helm.Options {
+	return helm.Options{
+		KubeconfigPath:       ctx.String("kubeconfig"),
+		Namespace:            ctx.String("namespace"),
+		RancherNamespaceOnly: !ctx.Bool("all-namespaces"),
+		ScanAllNamespaces:    ctx.Bool("scan-all-namespaces"),
+		OCIRepo:              ctx.String("oci-repo"),
+		RepoTimeout:          ctx.Duration("repo-timeout"),
+		SkipRepoUpdate:       ctx.Bool("skip-repo-update"),
+		HelmDriver:           ctx.String("helm-driver"),
+		StayOnMinor:          ctx.Bool("stay-on-minor"),
+		UpgradePolicy:        helm.UpgradePolicy(ctx.String("upgrade-policy")),
+		MaxIndexAge:          ctx.Duration("max-index-age"),
+		Verify:               ctx.Bool("verify"),
+		Keyring:              ctx.String("keyring"),
+		RepoUsername:         ctx.String("repo-username"),
+		RepoPassword:         ctx.String("repo-password"),
+		Proxy:                proxyFlags(ctx),
+	}
+}
+
+// proxyFlags returns the proxy passed on the command line, the zero Config leaves it to the environment.
+func proxyFlags(ctx *cli.Context) proxy.Config {
+	return proxy.Config{URL: ctx.String("proxy"), NoProxy: ctx.String("no-proxy")}
+}
+
+func (u *UpgradeActionClient) Init(opts helm.Options) error {
+	client, err := helm.NewClient(opts)
+	if err != nil {
+		return err
+	}
+	u.helmExecer = client
+	return nil
+}
+
+func (u *UpgradeActionClient) UpgradeRancher(ctx *cli.Context) (err error) {
+	u.runID = strings.TrimSpace(ctx.String("run-id"))
+	if u.runID == "" {
+		u.runID = uuid.NewString()
+	}
+	u.audit = newAuditEntry(ctx, u.clock.Now())
+	u.audit.RunID = u.runID
+	if auditLogPath := ctx.String("audit-log"); auditLogPath != "" {
+		defer func() {
+			u.audit.finish(err)
+			if auditErr := appendAuditEntry(auditLogPath, u.audit); auditErr != nil && err == nil {
+				err = auditErr
+			}
+		}()
+	}
+	defer func() {
+		if err == nil && len(u.unfetched) != 0 && !ctx.Bool("ignore-notes-errors") {
+			err = incompleteNotesError{versions: u.unfetched}
+		}
+	}()
+
+	u.report = upgradeReport{RunID: u.runID, GeneratedAt: u.clock.Now().UTC(), DryRun: ctx.Bool("dry-run")}
+	if reportPath := ctx.String("report"); reportPath != "" {
+		format := ctx.String("report-format")
+		if format != reportFormatHTML && format != reportFormatJSON {
+			return fmt.Errorf("unsupported report format [%s], must be one of: %s, %s", format, reportFormatHTML, reportFormatJSON)
+		}
+		defer func() {
+			if len(u.report.Hops) == 0 {
+				return
+			}
+			if reportErr := writeReport(reportPath, format, u.report); reportErr != nil && err == nil {
+				err = reportErr
+			}
+		}()
+	}
+
+	if eventsPath := ctx.String("events-stream"); eventsPath != "" {
+		var closeEvents func() error
+		u.events, closeEvents, err = openEventStream(eventsPath, u.runID, u.clock)
+		if err != nil {
+			return fmt.Errorf("failed to open the events stream [%s]: %w", eventsPath, err)
+		}
+		defer closeEvents()
+	}
+
+	// the values are the only thing written to stdout, everything else goes to stderr
+	valuesOnly := ctx.Bool("output-current-values-only")
+	valuesOut := os.Stdout
+	if valuesOnly {
+		if ctx.String("events-stream") == "-" {
+			return fmt.Errorf("--output-current-values-only and --events-stream=- cannot be used together, both write to stdout")
+		}
+		redirectOutputToStderr()
+	}
+
+	knownIssuesOnly := ctx.Bool("list-known-issues-only")
+	if !knownIssuesOnly && (ctx.IsSet("from") || ctx.IsSet("to")) {
+		return fmt.Errorf("--from and --to require --list-known-issues-only")
+	}
+	if knownIssuesOnly {
+		for _, conflicting := range []string{"output-current-values-only", "chart-path", "simulate", "record-session"} {
+			if ctx.IsSet(conflicting) {
+				return fmt.Errorf("--list-known-issues-only and --%s cannot be used together", conflicting)
+			}
+		}
+		// as with the values, the JSON list is the only thing written to stdout
+		if ctx.String("known-issues-format") == knownIssuesFormatJSON {
+			if ctx.String("events-stream") == "-" {
+				return fmt.Errorf("--known-issues-format=json and --events-stream=- cannot be used together, both write to stdout")
+			}
+			redirectOutputToStderr()
+		}
+	}
+
+	if ctx.Bool("since-last-upgrade") {
+		for _, conflicting := range []string{"list-known-issues-only", "output-current-values-only", "skip-notes", "chart-path", "from-revision", "latest", "target-app-version", "target-version-file"} {
+			if ctx.IsSet(conflicting) {
+				return fmt.Errorf("--since-last-upgrade and --%s cannot be used together", conflicting)
+			}
+		}
+	}
+
+	if ctx.Int("concurrency") < 1 {
+		return fmt.Errorf("--concurrency must be at least 1, got %d", ctx.Int("concurrency"))
+	}
+
+	u.explain = ctx.Bool("explain")
+	u.support, err = support.Load(ctx.String("support-matrix"))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(i18n.T(i18n.Welcome, emoji.CowboyHatFace))
+	fmt.Println(i18n.T(i18n.RunStarted, u.runID))
+	defer fmt.Println(i18n.T(i18n.RunFinished, u.runID))
+	u.explainStep(i18n.ExplainDetection)
+	fmt.Println(i18n.T(i18n.DetectingReleases, emoji.MagnifyingGlassTiltedLeft))
+
+	recordPath, simulatePath := ctx.String("record-session"), ctx.String("simulate")
+	if recordPath != "" && simulatePath != "" {
+		return fmt.Errorf("--record-session and --simulate cannot be used together")
+	}
+	if ctx.Bool("latest") && ctx.String("target-app-version") != "" {
+		return fmt.Errorf("--latest and --target-app-version cannot be used together")
+	}
+	targetVersionFile := ctx.String("target-version-file")
+	targetAppVersion := ctx.String("target-app-version")
+	if targetVersionFile != "" {
+		for _, conflicting := range []string{"target-app-version", "latest", "chart-path", "since-last-upgrade", "list-known-issues-only"} {
+			if ctx.IsSet(conflicting) {
+				return fmt.Errorf("--target-version-file and --%s cannot be used together", conflicting)
+			}
+		}
+		if targetAppVersion, err = readTargetVersionFile(targetVersionFile); err != nil {
+			return err
+		}
+	}
+	chartPath := ctx.String("chart-path")
+	if chartPath != "" {
+		for _, conflicting := range []string{"latest", "target-app-version", "stay-on-minor", "upgrade-policy", "oci-repo", "simulate"} {
+			if ctx.IsSet(conflicting) {
+				return fmt.Errorf("--chart-path and --%s cannot be used together", conflicting)
+			}
+		}
+	}
+	if ctx.Bool("skip-notes") {
+		for _, conflicting := range []string{"fail-on-known-issue", "list-known-issues-only", "ignore-notes-errors", "require-behavior-ack", "skip-empty-releases"} {
+			if ctx.IsSet(conflicting) {
+				return fmt.Errorf("--skip-notes and --%s cannot be used together, it needs the release notes", conflicting)
+			}
+		}
+	}
+	if ctx.IsSet("acked-behavior-changes") && !ctx.Bool("require-behavior-ack") {
+		return fmt.Errorf("--acked-behavior-changes requires --require-behavior-ack")
+	}
+	if ctx.IsSet("values-from-configmap") || ctx.IsSet("values-from-secret") {
+		for _, conflicting := range []string{"simulate", "output-current-values-only", "list-known-issues-only"} {
+			if ctx.IsSet(conflicting) {
+				return fmt.Errorf("--values-from-configmap and --values-from-secret cannot be used with --%s", conflicting)
+			}
+		}
+	}
+	if ctx.Int("upgrade-retries") > 0 && !ctx.Bool("atomic") {
+		return fmt.Errorf("--upgrade-retries requires --atomic, a failed upgrade must be rolled back before it is retried")
+	}
+
+	assumeYes := ctx.Bool("yes")
+	if !ctx.Bool("interactive") {
+		if ctx.IsSet("yes") && !assumeYes {
+			return fmt.Errorf("--interactive=false and --yes=false cannot be used together")
+		}
+		// sessions are made of typed answers, which a non-interactive run never reads
+		for _, conflicting := range []string{"record-session", "simulate"} {
+			if ctx.IsSet(conflicting) {
+				return fmt.Errorf("--interactive=false and --%s cannot be used together, the session needs typed answers", conflicting)
+			}
+		}
+		assumeYes = true
+		u.quiet = true
+	}
+	if ctx.Bool("quiet") {
+		if !assumeYes {
+			return fmt.Errorf("--quiet needs --yes or --interactive=false, the release notes it does not print cannot be confirmed")
+		}
+		u.quiet = true
+	}
+	// a kubeconfig read from stdin leaves nothing for the prompts to read, only unattended runs never prompt
+	if ctx.String("kubeconfig") == stdinKubeconfig && ctx.String("kubeconfig-data") == "" && !assumeYes {
+		return fmt.Errorf("--kubeconfig - needs --yes or --interactive=false, stdin is needed to answer the upgrade's prompts")
+	}
+	// checked before anything is looked up, rather than failing once the user has gone through every other prompt
+	if assumeYes && ctx.Bool("require-confirm-phrase") && !ctx.Bool("dry-run") {
+		return fmt.Errorf("--require-confirm-phrase needs the phrase typed in and cannot be used with --yes or --interactive=false")
+	}
+	// recorded answers are written out as typed, guided values for redacted keys such as passwords included
+	if ctx.Bool("guided-values") && recordPath != "" {
+		return fmt.Errorf("--guided-values and --record-session cannot be used together, the session would store the answers unredacted")
+	}
+	var input io.Reader = os.Stdin
+	var localChart *chart.Chart
+	interactive := isInteractive() && !assumeYes
+	if ctx.Bool("tui") {
+		// the TUI reads key presses, which a session cannot record or replay as typed answers
+		for _, conflicting := range []string{"record-session", "simulate"} {
+			if ctx.IsSet(conflicting) {
+				return fmt.Errorf("--tui and --%s cannot be used together, the session needs typed answers", conflicting)
+			}
+		}
+		u.tui = interactive && canUseTUI()
+		if !u.tui {
+			color.Yellow("%s", i18n.T(i18n.TUIUnavailable, emoji.Warning))
+		}
+	}
+	notesSource := githubReleaseNotes{}
+	if proxyConfig := proxyFlags(ctx); proxyConfig.IsSet() {
+		transport, err := proxyConfig.Transport()
+		if err != nil {
+			return err
+		}
+		notesSource.client = &http.Client{Transport: transport}
+	}
+	u.notes = notesSource
+	if cacheDir := ctx.String("notes-cache-dir"); cacheDir != "" {
+		u.notes = cachedReleaseNotes{source: u.notes, dir: cacheDir, clock: u.clock}
+	}
+	if knownIssuesOnly && ctx.String("from") != "" && ctx.String("to") != "" {
+		return u.listKnownIssues(ctx, ctx.String("from"), ctx.String("to"), valuesOut)
+	}
+	if simulatePath != "" {
+		recorded, err := loadSession(simulatePath)
+		if err != nil {
+			return err
+		}
+		replay := &sessionReplay{session: recorded}
+		u.helmExecer, u.notes = replay, replay
+		input = replay.input(os.Stdout)
+		// the recorded answers stand in for the user
+		interactive = true
+		fmt.Println(i18n.T(i18n.SimulatingSession, simulatePath))
+	} else {
+		kubeconfigPath, cleanup, err := resolveKubeconfig(ctx, os.Stdin)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		opts := clientOptions(ctx)
+		opts.KubeconfigPath = kubeconfigPath
+		opts.Clock = u.clock
+		if chartPath != "" {
+			if localChart, err = helm.LoadLocalChart(chartPath); err != nil {
+				return err
+			}
+			// the chart repository is not needed, which keeps air-gapped clusters without one working
+			client, err := helm.NewReleaseClient(opts)
+			if err != nil {
+				return err
+			}
+			u.helmExecer = localChartExecer{helmExecer: client, chart: localChart}
+		} else if valuesOnly {
+			// the chart repository is never consulted for the current values
+			if u.helmExecer, err = helm.NewReleaseClient(opts); err != nil {
+				return err
+			}
+		} else if err := u.Init(opts); err != nil {
+			var missingRepo *helm.MissingRepoError
+			if !errors.As(err, &missingRepo) || !interactive {
+				return err
+			}
+			// asked on stdin directly, the answer is not part of recorded sessions and simulations never look up the repo
+			added, addErr := offerToAddRancherStableRepo(missingRepo, newPrompter(os.Stdin, false))
+			if addErr != nil || !added {
+				return err
+			}
+			if err := u.Init(opts); err != nil {
+				return err
+			}
+		}
+		if recordPath != "" {
+			recorder := &sessionRecorder{
+				helmExecer: u.helmExecer,
+				notes:      u.notes,
+				session:    newSession(),
+				redactKeys: ctx.StringSlice("redact-keys"),
+			}
+			u.helmExecer, u.notes = recorder, recorder
+			input = recorder.input(os.Stdin)
+			defer func() {
+				if saveErr := recorder.save(recordPath); saveErr != nil && err == nil {
+					err = saveErr
+				}
+			}()
+		}
+	}
+
+	u.unattended = !interactive
+	reader := newPrompter(input, assumeYes)
+
+	selected, err := findRancherRelease(ctx, u.helmExecer, interactive, reader, os.Stdout)
+	if err != nil {
+		return err
+	}
+	targetRelease := selected.Release
+	if path := ctx.String("dump-release-json"); path != "" {
+		if err := dumpRelease(path, u.runID, targetRelease, ctx.StringSlice("redact-keys")); err != nil {
+			return fmt.Errorf("failed to dump release [%s] to [%s]: %w", targetRelease.Name, path, err)
+		}
+		fmt.Fprintln(os.Stderr, i18n.T(i18n.DumpedRelease, emoji.Package, targetRelease.Name, path))
+	}
+	if valuesOnly {
+		valuesBytes, err := renderValues(targetRelease.Chart, targetRelease.Config, false, ctx.String("output"), ctx.StringSlice("redact-keys"))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(valuesOut, string(valuesBytes))
+		return nil
+	}
+	if knownIssuesOnly {
+		from, to := ctx.String("from"), ctx.String("to")
+		if from == "" {
+			from = targetRelease.Chart.Metadata.Version
+		}
+		if to == "" {
+			if to, err = u.helmExecer.GetNextSupportedRancherChartVersion(from); err != nil {
+				return err
+			}
+			if to == from {
+				fmt.Print(i18n.T(i18n.UpToDate, emoji.PartyingFace))
+				return nil
+			}
+		}
+		return u.listKnownIssues(ctx, from, to, valuesOut)
+	}
+	if ctx.Bool("since-last-upgrade") {
+		return u.reviewSinceLastUpgrade(ctx, targetRelease, reader)
+	}
+	// read before anything is reviewed, so a missing resource or broken YAML fails the run right away
+	if u.clusterValues, err = readClusterValues(u.helmExecer, ctx.StringSlice("values-from-configmap"), ctx.StringSlice("values-from-secret")); err != nil {
+		return err
+	}
+	targetRelease, err = u.selectBaseRevision(targetRelease, ctx.Int("from-revision"), reader)
+	if err != nil {
+		return err
+	}
+	if targetRelease == nil {
+		return nil
+	}
+	fmt.Println(i18n.T(i18n.ConfirmRelease, targetRelease.Name, targetRelease.Namespace))
+	currentVersion := targetRelease.Chart.Metadata.Version
+	u.audit.Release = targetRelease.Name
+	u.audit.Namespace = targetRelease.Namespace
+	u.audit.FromVersion = currentVersion
+	u.report.Release, u.report.Namespace = targetRelease.Name, targetRelease.Namespace
+	u.events.emit(event{Event: eventDetectedRelease, Release: targetRelease.Name, Namespace: targetRelease.Namespace, Version: currentVersion})
+
+	if localChart != nil {
+		return u.upgradeToLocalChart(ctx, targetRelease, localChart, reader)
+	}
+	u.explainStep(i18n.ExplainVersionSelection)
+	if ctx.Bool("latest") {
+		return u.upgradeToLatest(ctx, targetRelease, reader)
+	}
+	if targetAppVersion != "" {
+		if targetVersionFile != "" {
+			if _, err := u.helmExecer.GetRancherChartForAppVersion(targetAppVersion); err != nil {
+				return reportOtherRepos(ctx, targetAppVersion, fmt.Errorf("rancher version [%s] from --target-version-file [%s] is not available: %w", targetAppVersion, targetVersionFile, err))
+			}
+		}
+		return u.upgradeToAppVersion(ctx, targetRelease, targetAppVersion, reader)
+	}
+
+	nextSupportedChartVersion, err := u.helmExecer.GetNextSupportedRancherChartVersion(targetRelease.Chart.Metadata.Version)
+	if err != nil {
+		return err
+	}
+
+	if currentVersion == nextSupportedChartVersion {
+		u.audit.Outcome = outcomeUpToDate
+		fmt.Print(i18n.T(i18n.UpToDate, emoji.PartyingFace))
+		return nil
+	}
+
+	u.events.emit(event{Event: eventComputedTarget, FromVersion: currentVersion, ToVersion: nextSupportedChartVersion})
+	if err := checkSupport(u.support, currentVersion, nextSupportedChartVersion, ctx.Bool("enforce-support"), u.clock.Now()); err != nil {
+		return err
+	}
+
+	latestStableRancherChart, err := u.helmExecer.GetRancherChartForVersion(nextSupportedChartVersion)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(i18n.T(i18n.NextAvailableUpdate, formatChartVersion(targetRelease.Chart.Metadata), formatChartVersion(latestStableRancherChart.Metadata)))
+
+	cont, err := promptForContinue(reader)
+	if err != nil {
+		return err
+	}
+	if !cont {
+		return nil
+	}
+
+	_, err = u.upgradeTo(ctx, targetRelease, latestStableRancherChart.Version, reader)
+	return err
+}
+
+// reportOtherRepos lists the other configured repos serving version, which the rancher-stable repo does not have,
+// when --search-all-repos is set. err, the lookup error, is returned as is.
+func reportOtherRepos(ctx *cli.Context, version string, err error) error {
+	if !ctx.Bool("search-all-repos") {
+		return err
+	}
+	found, searchErr := helm.FindRancherChartInOtherRepos(version)
+	if searchErr != nil {
+		color.Yellow("%s", i18n.T(i18n.OtherReposSearchFailed, emoji.Warning, searchErr))
+		return err
+	}
+	if len(found) == 0 {
+		fmt.Println(i18n.T(i18n.NotInOtherRepos, version))
+		return err
+	}
+	fmt.Println(i18n.T(i18n.FoundInOtherRepos, version))
+	for _, chartVersion := range found {
+		fmt.Println(i18n.T(i18n.OtherRepoChart, chartVersion.Repo, chartVersion.URL, chartVersion.Version, chartVersion.AppVersion))
+	}
+	return err
+}
+
+// offerToAddRancherStableRepo asks the user whether to add the missing rancher-stable repo and adds it if they agree.
+func offerToAddRancherStableRepo(missingRepo *helm.MissingRepoError, reader *prompter) (bool, error) {
+	color.Yellow("%s", missingRepo.Error())
+	fmt.Print(i18n.T(i18n.OfferAddRepo, missingRepo.Remediation()))
+	add, err := promptForContinue(reader)
+	if err != nil || !add {
+		return false, err
+	}
+	if err := helm.AddRancherStableRepo(); err != nil {
+		color.Red("%s", err.Error())
+		return false, err
+	}
+	fmt.Println(i18n.T(i18n.AddedRepo, emoji.ThumbsUp))
+	return true, nil
+}
+
+// selectBaseRevision returns the revision of targetRelease the upgrade should start from. An explicit fromRevision is
+// validated against the release history. Otherwise, when the latest revision failed, the user is offered the last
+// revision that was deployed. The returned release is nil when the user chose not to continue.
+func (u *UpgradeActionClient) selectBaseRevision(targetRelease *release.Release, fromRevision int, reader *prompter) (*release.Release, error) {
+	if fromRevision == 0 && targetRelease.Info.Status != release.StatusFailed {
+		return targetRelease, nil
+	}
+
+	revisions, err := u.helmExecer.History(targetRelease.Namespace, targetRelease.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if fromRevision != 0 {
+		if len(revisions) == 0 {
+			return nil, fmt.Errorf("revision [%d] not found, release [%s] has no stored revisions", fromRevision, targetRelease.Name)
+		}
+		// helm prunes old revisions past --history-max, so the stored ones need not start at 1
+		existing := make([]string, 0, len(revisions))
+		for _, revision := range revisions {
+			if revision.Version == fromRevision {
+				fmt.Println(i18n.T(i18n.BasingOnRevision, revision.Version, formatChartVersion(revision.Chart.Metadata), revision.Info.Status))
+				return revision, nil
+			}
+			existing = append(existing, strconv.Itoa(revision.Version))
+		}
+		return nil, fmt.Errorf("revision [%d] not found in the history of release [%s], the stored revisions are: %s", fromRevision, targetRelease.Name, strings.Join(existing, ", "))
+	}
+
+	color.Yellow("%s", i18n.T(i18n.LatestRevisionFailed, emoji.Warning, targetRelease.Version, targetRelease.Name))
+	for i := len(revisions) - 1; i >= 0; i-- {
+		revision := revisions[i]
+		if revision.Info.Status != release.StatusDeployed && revision.Info.Status != release.StatusSuperseded {
+			continue
+		}
+		fmt.Print(i18n.T(i18n.OfferDeployedRevision, revision.Version, formatChartVersion(revision.Chart.Metadata)))
+		useDeployed, err := promptForContinue(reader)
+		if err != nil {
+			return nil, err
+		}
+		if useDeployed {
+			return revision, nil
+		}
+		break
+	}
+
+	fmt.Print(i18n.T(i18n.ContinueWithFailedRevision, targetRelease.Version))
+	cont, err := promptForContinue(reader)
+	if err != nil || !cont {
+		return nil, err
+	}
+	return targetRelease, nil
+}
+
+// readTargetVersionFile returns the rancher version in the file at path, e.g. v2.7.9. Surrounding whitespace is
+// ignored and the leading v is optional.
+func readTargetVersionFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read --target-version-file: %w", err)
+	}
+	version := strings.TrimSpace(string(data))
+	if version == "" {
+		return "", fmt.Errorf("--target-version-file [%s] is empty, it must hold the rancher version to upgrade to, e.g. v2.7.9", path)
+	}
+	parsed, err := semver.Parse(strings.TrimPrefix(version, "v"))
+	if err != nil {
+		return "", fmt.Errorf("--target-version-file [%s] holds [%s], which is not a rancher version like v2.7.9: %w", path, version, err)
+	}
+	return "v" + parsed.String(), nil
+}
+
+// reviewSinceLastUpgrade walks through the notes of the releases between the chart version targetRelease was last
+// upgraded from and the installed one. Revisions that only changed values are skipped, so the range covers the last
+// intentional upgrade even when values were changed since.
+func (u *UpgradeActionClient) reviewSinceLastUpgrade(ctx *cli.Context, targetRelease *release.Release, reader *prompter) error {
+	revisions, err := u.helmExecer.History(targetRelease.Namespace, targetRelease.Name)
+	if err != nil {
+		return err
+	}
+	currentVersion := targetRelease.Chart.Metadata.Version
+	previous := lastUpgradedFrom(revisions, targetRelease)
+	if previous == nil {
+		fmt.Println(i18n.T(i18n.NoPreviousUpgrade, targetRelease.Name, currentVersion))
+		return nil
+	}
+
+	previousVersion := previous.Chart.Metadata.Version
+	from, err := semver.Parse(previousVersion)
+	if err != nil {
+		return err
+	}
+	current, err := semver.Parse(currentVersion)
+	if err != nil {
+		return err
+	}
+	if !current.GT(from) {
+		color.Yellow("%s", i18n.T(i18n.LastChangeWasDowngrade, emoji.Warning, previous.Version, previousVersion, currentVersion))
+		return nil
+	}
+
+	fmt.Println(i18n.T(i18n.ReviewingSinceLastUpgrade, previous.Version, formatChartVersion(previous.Chart.Metadata), formatChartVersion(targetRelease.Chart.Metadata)))
+	u.audit.Release, u.audit.Namespace = targetRelease.Name, targetRelease.Namespace
+	u.audit.FromVersion, u.audit.ToVersion = previousVersion, currentVersion
+	u.report.Release, u.report.Namespace = targetRelease.Name, targetRelease.Namespace
+	_, cont, err := u.reviewNotes(ctx, previousVersion, currentVersion, reader)
+	if err != nil || !cont {
+		return err
+	}
+	u.audit.Outcome = outcomeReviewed
+	fmt.Println(i18n.T(i18n.ReviewedSinceLastUpgrade, emoji.CheckMarkButton, previousVersion, currentVersion))
+	return nil
+}
+
+// lastUpgradedFrom returns the newest revision before targetRelease that was deployed with another chart version, or
+// nil when the chart version never changed.
+func lastUpgradedFrom(revisions []*release.Release, targetRelease *release.Release) *release.Release {
+	installedVersion := targetRelease.Chart.Metadata.Version
+	for i := len(revisions) - 1; i >= 0; i-- {
+		revision := revisions[i]
+		if revision.Version >= targetRelease.Version || revision.Chart == nil || revision.Chart.Metadata == nil {
+			continue
+		}
+		// failed and pending revisions never ran, so they are not what was upgraded from
+		if revision.Info == nil || (revision.Info.Status != release.StatusSuperseded && revision.Info.Status != release.StatusDeployed) {
+			continue
+		}
+		if revision.Chart.Metadata.Version != installedVersion {
+			return revision
+		}
+	}
+	return nil
+}
+
+// upgradeToLatest upgrades through every hop of the supported upgrade path until the newest version is reached.
+func (u *UpgradeActionClient) upgradeToLatest(ctx *cli.Context, targetRelease *release.Release, reader *prompter) error {
+	currentVersion := targetRelease.Chart.Metadata.Version
+	upgradePath, err := u.helmExecer.GetUpgradePath(currentVersion)
+	if err != nil {
+		return err
+	}
+
+	if len(upgradePath) == 0 {
+		u.audit.Outcome = outcomeUpToDate
+		fmt.Print(i18n.T(i18n.UpToDate, emoji.PartyingFace))
+		return nil
+	}
+
+	fmt.Println(i18n.T(i18n.UpgradePlan, upgradePath[len(upgradePath)-1], len(upgradePath)))
+	return u.upgradeAlong(ctx, targetRelease, upgradePath, reader)
+}
+
+// upgradeToAppVersion upgrades through every hop of the supported upgrade path until the chart version shipping
+// rancher appVersion is reached.
+func (u *UpgradeActionClient) upgradeToAppVersion(ctx *cli.Context, targetRelease *release.Release, appVersion string, reader *prompter) error {
+	currentVersion := targetRelease.Chart.Metadata.Version
+	targetChart, err := u.helmExecer.GetRancherChartForAppVersion(appVersion)
+	if err != nil {
+		return reportOtherRepos(ctx, appVersion, err)
+	}
+	fmt.Println(i18n.T(i18n.ResolvedAppVersion, appVersion, targetChart.Version))
+
+	current, err := semver.Parse(currentVersion)
+	if err != nil {
+		return err
+	}
+	target, err := semver.Parse(targetChart.Version)
+	if err != nil {
+		return err
+	}
+	if target.Equals(current) {
+		u.audit.Outcome = outcomeUpToDate
+		fmt.Print(i18n.T(i18n.UpToDate, emoji.PartyingFace))
+		return nil
+	}
+	if target.LT(current) {
+		return fmt.Errorf("rancher [%s] is chart version [%s], older than the installed chart version [%s], downgrades are not supported", appVersion, targetChart.Version, currentVersion)
+	}
+
+	if ctx.Bool("stay-on-minor") && (target.Major != current.Major || target.Minor != current.Minor) {
+		return fmt.Errorf("chart version [%s] is not on the installed minor version [%d.%d.x] and --stay-on-minor is set", targetChart.Version, current.Major, current.Minor)
+	}
+
+	latestPath, err := u.helmExecer.GetUpgradePath(currentVersion)
+	if err != nil {
+		return err
+	}
+	upgradePath, err := supportedPathTo(current, target, latestPath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(i18n.T(i18n.TargetUpgradePlan, targetChart.Version, len(upgradePath)))
+	return u.upgradeAlong(ctx, targetRelease, upgradePath, reader)
+}
+
+// supportedPathTo follows latestPath, the supported upgrade path from current, until target can be upgraded to
+// directly and returns the hops up to and including target.
+func supportedPathTo(current, target semver.Version, latestPath []string) ([]string, error) {
+	var upgradePath []string
+	hop := current
+	for _, version := range append(latestPath, "") {
+		if directlyUpgradable(hop, target) {
+			return append(upgradePath, target.String()), nil
+		}
+		if version == "" {
+			break
+		}
+		upgradePath = append(upgradePath, version)
+		var err error
+		if hop, err = semver.Parse(version); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("chart version [%s] is not on the supported upgrade path from [%s]: %s", target, current,
+		strings.Join(append([]string{current.String()}, latestPath...), " -> "))
+}
+
+// directlyUpgradable reports whether target can be upgraded to from current in a single hop, a newer patch of the
+// same minor or any patch of the next minor.
+func directlyUpgradable(current, target semver.Version) bool {
+	return target.Major == current.Major && (target.Minor == current.Minor || target.Minor == current.Minor+1)
+}
+
+// upgradeAlong shows the upgrade path, asks to continue and upgrades through each of its hops.
+func (u *UpgradeActionClient) upgradeAlong(ctx *cli.Context, targetRelease *release.Release, upgradePath []string, reader *prompter) error {
+	currentVersion := targetRelease.Chart.Metadata.Version
+	u.events.emit(event{Event: eventComputedTarget, FromVersion: currentVersion, ToVersion: upgradePath[len(upgradePath)-1]})
+	// only the final target matters, the hops before it are required by the upgrade path
+	if err := checkSupport(u.support, currentVersion, upgradePath[len(upgradePath)-1], ctx.Bool("enforce-support"), u.clock.Now()); err != nil {
+		return err
+	}
+
+	fmt.Println(strings.Join(append([]string{currentVersion}, upgradePath...), " -> "))
+
+	cont, err := promptForContinue(reader)
+	if err != nil {
+		return err
+	}
+	if !cont {
+		return nil
+	}
 
-type helmExecer interface {
-	FindRancherRelease() (*release.Release, error)
-	GetNextSupportedRancherChartVersion(currentVersion string) (string, error)
-	GetRancherChartForVersion(version string) (*repo.ChartVersion, error)
-	Upgrade(release *release.Release, overrideValues map[string]interface{}) (*release.Release, error)
+	for index, version := range upgradePath {
+		fmt.Printf("\n%s\n", i18n.T(i18n.UpgradeHop, index+1, len(upgradePath), targetRelease.Chart.Metadata.Version, version))
+		targetRelease, err = u.upgradeTo(ctx, targetRelease, version, reader)
+		if err != nil {
+			return err
+		}
+		if targetRelease == nil {
+			return nil
+		}
+	}
+	return nil
 }
 
-type UpgradeActionClient struct {
-	helmExecer helmExecer
-}
+// reviewNotes fetches the notes of the releases after currentVersion up to version and walks the user through them. It
+// returns the report hop of the upgrade and whether the user wants to continue.
+func (u *UpgradeActionClient) reviewNotes(ctx *cli.Context, currentVersion, version string, reader *prompter) (*reportHop, bool, error) {
+	releaseSemverStrings, err := getReleasesBetweenInclusive(currentVersion, version)
+	if err != nil {
+		return nil, false, err
+	}
 
-func UpgradeCommand() *cli.Command {
-	flags := []cli.Flag{
-		&cli.StringFlag{
-			Name:     "kubeconfig",
-			Usage:    "Specify kubeconfig path",
-			Value:    "",
-			EnvVars:  []string{"KUBECONFIG"},
-			Required: true,
-		},
+	available := configuredNoteSections(ctx)
+	sections, err := selectNoteSections(available, ctx.StringSlice("sections"))
+	if err != nil {
+		return nil, false, err
 	}
 
-	c := &UpgradeActionClient{}
-	return &cli.Command{
-		Name:   "upgrade",
-		Usage:  "Bring the cluster up",
-		Action: c.UpgradeRancher,
-		Flags:  flags,
+	since, err := parseSince(ctx.String("since"))
+	if err != nil {
+		return nil, false, err
+	}
+
+	var knownIssueGate *regexp.Regexp
+	parsedSections := sections
+	var ackedBehaviorChanges map[string]bool
+	if ctx.Bool("require-behavior-ack") && u.unattended {
+		if ackedBehaviorChanges, err = loadAckedBehaviorChanges(ctx.String("acked-behavior-changes")); err != nil {
+			return nil, false, err
+		}
+		// the gate needs the behavior changes even when they are not reviewed
+		if !hasSection(sections, sectionBehaviorChanges) {
+			for _, section := range available {
+				if section.name == sectionBehaviorChanges {
+					parsedSections = append(append([]noteSection(nil), parsedSections...), section)
+				}
+			}
+		}
+	}
+	if pattern := ctx.String("fail-on-known-issue"); pattern != "" {
+		knownIssueGate, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid --fail-on-known-issue expression [%s]: %w", pattern, err)
+		}
+		// the gate needs the known issues even when they are not reviewed
+		if !hasSection(sections, sectionKnownIssues) {
+			for _, section := range available {
+				if section.name == sectionKnownIssues {
+					parsedSections = append(append([]noteSection(nil), parsedSections...), section)
+				}
+			}
+		}
 	}
-}
 
-func (u *UpgradeActionClient) Init(kubeconfigPath string) error {
-	client, err := helm.NewClient(kubeconfigPath)
+	u.events.emit(event{Event: eventFetchingNotes, FromVersion: currentVersion, ToVersion: version})
+	notes, err := parseReleaseNotes(u.notes, releaseSemverStrings, parsedSections, ctx.Int("concurrency"))
 	if err != nil {
-		return err
+		return nil, false, err
+	}
+
+	if knownIssueGate != nil {
+		if matched := matchingKnownIssues(notes, knownIssueGate); len(matched) != 0 {
+			color.Red("%s", i18n.T(i18n.KnownIssueGateFailed, emoji.StopSign, len(matched), knownIssueGate))
+			for _, issue := range matched {
+				fmt.Println(issue)
+			}
+			return nil, false, cli.Exit(fmt.Sprintf("%d known issue(s) match --fail-on-known-issue [%s]", len(matched), knownIssueGate), exitCodeKnownIssueGate)
+		}
+	}
+
+	if ackedBehaviorChanges != nil {
+		if unacked := unackedBehaviorChanges(notes, ackedBehaviorChanges); len(unacked) != 0 {
+			color.Red("%s", i18n.T(i18n.BehaviorAckGateFailed, emoji.StopSign, len(unacked)))
+			for _, change := range unacked {
+				fmt.Println(change)
+			}
+			return nil, false, cli.Exit(fmt.Sprintf("%d behavior change(s) are not acknowledged in --acked-behavior-changes", len(unacked)), exitCodeBehaviorAckGate)
+		}
+	}
+
+	if unfetched := unfetchedNotes(notes[1:]); len(unfetched) != 0 {
+		displayUnfetchedNotes(unfetched)
+		for _, note := range unfetched {
+			u.unfetched = append(u.unfetched, note.version)
+		}
+		if ctx.Bool("ignore-notes-errors") {
+			fmt.Println(i18n.T(i18n.IgnoringNotesErrors))
+		}
 	}
-	u.helmExecer = client
-	return nil
-}
 
-func (u *UpgradeActionClient) UpgradeRancher(ctx *cli.Context) error {
-	fmt.Printf("Welcome to rancher upgrader %v\n", emoji.CowboyHatFace)
-	fmt.Printf("%v Detecting rancher releases...\n", emoji.MagnifyingGlassTiltedLeft)
+	u.explainStep(i18n.ExplainNoteReview)
+	hop := u.report.addHop(notes, sections)
+	walkthrough := walkthroughRelevantNotes
+	if u.tui {
+		walkthrough = walkthroughTUI
+	} else if u.quiet {
+		walkthrough = quietWalkthrough(walkthrough)
+	}
+	cont, acknowledged, err := walkthrough(notes, sections, since, ctx.StringSlice("breaking-keywords"), ctx.Bool("skip-empty-releases"), reader, u.events)
+	u.audit.AcknowledgedKnownIssues = append(u.audit.AcknowledgedKnownIssues, acknowledged...)
+	hop.AcknowledgedKnownIssues = acknowledged
+	if err != nil {
+		return nil, false, err
+	}
+	if !cont {
+		return nil, false, nil
+	}
 
-	u.Init(ctx.String("kubeconfig"))
+	if _, ok := notes[0].sections[sectionInstallNotes]; ok && !u.quiet {
+		fmt.Println()
+		displayImpact(estimateImpact(notes, ctx.StringSlice("impact-keywords")))
+	}
+	return hop, true, nil
+}
 
-	targetRelease, err := u.helmExecer.FindRancherRelease()
+// appVersionDowngrade reports whether the target chart ships an older rancher than the installed one, which a newer
+// chart version only does when it was mispackaged. AppVersions that are not semantic versions are not compared.
+func appVersionDowngrade(installed, target *chart.Metadata) bool {
+	installedApp, err := semver.ParseTolerant(installed.AppVersion)
 	if err != nil {
-		return err
+		return false
+	}
+	targetApp, err := semver.ParseTolerant(target.AppVersion)
+	if err != nil {
+		return false
+	}
+	return targetApp.LT(installedApp)
+}
+
+// confirmAppVersionDowngrade warns when upgrading the installed chart to target would downgrade rancher and asks
+// whether to upgrade anyway. Without a downgrade it returns true without asking. Unattended runs are refused with an
+// error rather than answered yes, unless allowDowngrade is set.
+func confirmAppVersionDowngrade(installed, target *chart.Metadata, allowDowngrade bool, reader *prompter) (bool, error) {
+	if !appVersionDowngrade(installed, target) {
+		return true, nil
 	}
+	color.Red("%s", i18n.T(i18n.AppVersionDowngrade, emoji.StopSign, target.Version, target.AppVersion, installed.Version, installed.AppVersion))
+	if allowDowngrade {
+		fmt.Println(i18n.T(i18n.AllowingAppVersionDowngrade))
+		return true, nil
+	}
+	if reader.assumeYes {
+		return false, fmt.Errorf("chart version [%s] would downgrade rancher from [%s] to [%s], pass --allow-app-version-downgrade to upgrade anyway in unattended runs", target.Version, installed.AppVersion, target.AppVersion)
+	}
+	fmt.Print(i18n.T(i18n.AcknowledgeAppVersionDowngrade))
+	return promptForContinue(reader)
+}
+
+// upgradeTo walks the user through the notes and values for a single upgrade of targetRelease to version and then
+// performs it. The returned release is nil when the user chose not to continue.
+func (u *UpgradeActionClient) upgradeTo(ctx *cli.Context, targetRelease *release.Release, version string, reader *prompter) (*release.Release, error) {
 	currentVersion := targetRelease.Chart.Metadata.Version
 
-	nextSupportedChartVersion, err := u.helmExecer.GetNextSupportedRancherChartVersion(targetRelease.Chart.Metadata.Version)
+	labels, err := parseLabels(ctx.StringSlice("label"))
 	if err != nil {
-		return err
+		return nil, err
+	}
+	description := ctx.String("description")
+	if description == "" {
+		description = fmt.Sprintf("Upgraded by rancher-upgrader from [%s] to [%s]", currentVersion, version)
 	}
 
-	if currentVersion == nextSupportedChartVersion {
-		fmt.Printf("%v Your rancher install is already up to date!", emoji.PartyingFace)
-		return nil
+	if err := u.checkWebhook(currentVersion, version, ctx.Bool("enforce-webhook-compat")); err != nil {
+		return nil, err
 	}
 
-	latestStableRancherChart, err := u.helmExecer.GetRancherChartForVersion(nextSupportedChartVersion)
+	diffOpts, err := newDiffOptions(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	fmt.Printf("Next available update from version [%s] to version [%s].\n", currentVersion, latestStableRancherChart.Version)
+	var hop *reportHop
+	if ctx.Bool("skip-notes") {
+		color.Yellow("%s", i18n.T(i18n.NotesSkipped, emoji.Warning, currentVersion, version))
+		hop = u.report.addSkippedHop(currentVersion, version)
+	} else {
+		var cont bool
+		hop, cont, err = u.reviewNotes(ctx, currentVersion, version, reader)
+		if err != nil {
+			return nil, err
+		}
+		if !cont {
+			return nil, nil
+		}
+	}
 
-	reader := bufio.NewReader(os.Stdin)
-	cont, err := promptForContinue(reader)
+	targetChart, err := u.helmExecer.LoadRancherChart(version)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if !cont {
-		return nil
+	if cont, err := confirmAppVersionDowngrade(targetRelease.Chart.Metadata, targetChart.Metadata, ctx.Bool("allow-app-version-downgrade"), reader); err != nil || !cont {
+		return nil, err
 	}
 
-	releaseSemverStrings, err := getReleasesBetweenInclusive(currentVersion, latestStableRancherChart.Version)
+	fmt.Println()
+	hop.DefaultValues, err = displayDefaultValuesDiff(targetRelease.Chart, targetChart, diffOpts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	bugfixes, knownIssues, err := parseReleaseNotes(releaseSemverStrings)
+	fmt.Println()
+	u.explainStep(i18n.ExplainValues)
+	currentValues := targetRelease.Config
+	if u.clusterValues != nil {
+		// merged into a copy, the release config is still compared against and recorded afterwards
+		currentValues = mergeValues(copyValues(targetRelease.Config), u.clusterValues)
+	}
+	overrideValues, err := chartValuesPrompt(targetChart, currentValues, ctx.StringSlice("redact-keys"), ctx.Bool("guided-values"), reader)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if ctx.Bool("lint") {
+		if err := u.lintTargetChart(targetRelease, targetChart, overrideValues); err != nil {
+			return nil, err
+		}
 	}
 
-	cont, err = walkthroughRelevantNotes(releaseSemverStrings, bugfixes, knownIssues, reader)
+	dryRun := ctx.Bool("dry-run")
+	if !dryRun && ctx.Bool("require-confirm-phrase") {
+		phrase := ctx.String("confirm-phrase")
+		if phrase == "" {
+			phrase = targetRelease.Name
+		}
+		confirmed, err := promptForConfirmPhrase(targetRelease, phrase, reader)
+		if err != nil {
+			return nil, err
+		}
+		if !confirmed {
+			fmt.Println(i18n.T(i18n.ConfirmPhraseMismatch))
+			return nil, nil
+		}
+	}
+	if !dryRun {
+		upgraded, err := u.upgradedMeanwhile(targetRelease, version)
+		if err != nil {
+			return nil, err
+		}
+		if upgraded {
+			u.audit.Outcome = outcomeUpToDate
+			fmt.Println(i18n.T(i18n.UpgradedMeanwhile, emoji.PartyingFace, targetRelease.Name, version))
+			return nil, nil
+		}
+	}
+	if _, replaying := u.helmExecer.(*sessionReplay); !dryRun && !replaying && ctx.String("snapshot-dir") != "" {
+		snapshotDir, err := u.snapshotRelease(ctx.String("snapshot-dir"), targetRelease, ctx.StringSlice("redact-keys"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot release [%s] before upgrading it: %w", targetRelease.Name, err)
+		}
+		fmt.Println(i18n.T(i18n.SavedSnapshot, emoji.Camera, targetRelease.Name, snapshotDir))
+	}
+	u.explainStep(i18n.ExplainUpgrade)
+	u.events.emit(event{Event: eventUpgradeStarted, Release: targetRelease.Name, Namespace: targetRelease.Namespace, FromVersion: currentVersion, ToVersion: version, DryRun: boolPointer(dryRun)})
+	newRelease, err := u.helmExecer.Upgrade(targetRelease, targetChart, overrideValues, helm.UpgradeOptions{
+		DryRun:       dryRun,
+		PostRenderer: ctx.String("post-renderer"),
+		Description:  description,
+		Labels:       labels,
+		Atomic:       ctx.Bool("atomic"),
+		Retries:      ctx.Int("upgrade-retries"),
+		RetryBackoff: ctx.Duration("upgrade-retry-backoff"),
+	})
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	u.audit.ToVersion = newRelease.Chart.Metadata.Version
+	u.audit.Outcome = outcomeSucceeded
+	u.events.emit(event{Event: eventUpgradeCompleted, Release: newRelease.Name, Namespace: newRelease.Namespace, FromVersion: currentVersion,
+		ToVersion: newRelease.Chart.Metadata.Version, DryRun: boolPointer(dryRun), Revision: newRelease.Version})
+
+	if dryRun {
+		if err := displayManifestDiff(targetRelease.Manifest, newRelease.Manifest, diffOpts); err != nil {
+			return nil, err
+		}
+		displayUpgradeHooks(newRelease.Hooks)
+		fmt.Println(i18n.T(i18n.DryRunComplete, emoji.MagnifyingGlassTiltedLeft, formatChartVersion(targetRelease.Chart.Metadata), formatChartVersion(newRelease.Chart.Metadata)))
+		displayChartNotes(newRelease, i18n.DryRunChartNotesHeader)
+		return newRelease, nil
+	}
+
+	if path := ctx.String("save-values"); path != "" {
+		if err := saveValues(path, overrideValues, saveValuesRedactKeys(ctx)); err != nil {
+			return nil, fmt.Errorf("the upgrade succeeded, but saving its values to [%s] failed: %w", path, err)
+		}
+		fmt.Println(i18n.T(i18n.SavedValues, path))
+	}
+
+	if check := ctx.String("post-upgrade-check"); check != "" {
+		if err := u.runPostUpgradeCheck(check, targetRelease, reader); err != nil {
+			return nil, err
+		}
 	}
 
+	fmt.Println(i18n.T(i18n.UpgradeSucceeded, emoji.PartyPopper, emoji.Fireworks, formatChartVersion(targetRelease.Chart.Metadata), formatChartVersion(newRelease.Chart.Metadata)))
+	displayChartNotes(newRelease, i18n.ChartNotesHeader)
+
+	return newRelease, nil
+}
+
+// displayChartNotes shows the chart's rendered NOTES.txt, which holds its own post-upgrade instructions such as how
+// to reach the UI.
+func displayChartNotes(upgraded *release.Release, header i18n.Message) {
+	if upgraded.Info == nil || strings.TrimSpace(upgraded.Info.Notes) == "" {
+		return
+	}
 	fmt.Println()
-	overrideValues, err := chartValuesPrompt(targetRelease.Chart, targetRelease.Config, reader)
+	color.Cyan("%s", i18n.T(header))
+	fmt.Println(strings.TrimRight(upgraded.Info.Notes, "\n"))
+}
+
+// upgradedMeanwhile reports whether a newer revision of targetRelease is already at version, e.g. because a retried
+// pipeline upgraded it while this run was reviewing the notes. Upgrading again would only add a revision that changes
+// nothing. Replayed sessions never touch a cluster, so nothing is checked for them.
+func (u *UpgradeActionClient) upgradedMeanwhile(targetRelease *release.Release, version string) (bool, error) {
+	if _, replaying := u.helmExecer.(*sessionReplay); replaying {
+		return false, nil
+	}
+	revisions, err := u.helmExecer.History(targetRelease.Namespace, targetRelease.Name)
 	if err != nil {
-		return err
+		return false, err
+	}
+	if len(revisions) == 0 {
+		return false, nil
+	}
+	latest := revisions[len(revisions)-1]
+	return latest.Version > targetRelease.Version && latest.Chart != nil && latest.Chart.Metadata.Version == version &&
+		latest.Info != nil && latest.Info.Status == release.StatusDeployed, nil
+}
+
+// lintTargetChart renders the target chart with the proposed values and reports any rendering errors before the
+// upgrade is attempted.
+func (u *UpgradeActionClient) lintTargetChart(targetRelease *release.Release, targetChart *chart.Chart, overrideValues map[string]interface{}) error {
+	fmt.Println(i18n.T(i18n.LintingChart, targetChart.Metadata.Version))
+	manifest, err := u.helmExecer.Render(targetRelease, targetChart, overrideValues)
+	if err != nil {
+		color.Red("%s", i18n.T(i18n.LintFailed, emoji.CrossMark, err))
+		return fmt.Errorf("rancher chart [%s] failed to render with the proposed values: %w", targetChart.Metadata.Version, err)
+	}
+	fmt.Println(i18n.T(i18n.LintPassed, emoji.CheckMarkButton, len(releaseutil.SplitManifests(manifest))))
+	return nil
+}
+
+// runPostUpgradeCheck runs the user's smoke test and shows its output. When it fails the user is offered to roll back
+// to previousRelease, and the run fails either way.
+func (u *UpgradeActionClient) runPostUpgradeCheck(check string, previousRelease *release.Release, reader *prompter) error {
+	fmt.Println(i18n.T(i18n.RunningPostUpgradeCheck, check))
+	output, checkErr := shellCommand(check).CombinedOutput()
+	fmt.Print(string(output))
+	if checkErr == nil {
+		fmt.Println(i18n.T(i18n.PostUpgradeCheckPassed, emoji.CheckMarkButton))
+		return nil
 	}
 
-	targetRelease.Chart.Metadata.Version = latestStableRancherChart.Version
-	newRelease, err := u.helmExecer.Upgrade(targetRelease, overrideValues)
+	color.Red("%s", i18n.T(i18n.PostUpgradeCheckFailed, emoji.CrossMark, checkErr))
+	fmt.Print(i18n.T(i18n.OfferRollback, previousRelease.Name, previousRelease.Version))
+	rollback, err := promptForContinue(reader)
 	if err != nil {
 		return err
 	}
+	if rollback {
+		if err := u.helmExecer.Rollback(previousRelease.Namespace, previousRelease.Name, previousRelease.Version); err != nil {
+			return fmt.Errorf("post-upgrade check [%s] failed: %v, and rolling back to revision [%d] failed: %w", check, checkErr, previousRelease.Version, err)
+		}
+		fmt.Println(i18n.T(i18n.RolledBack, previousRelease.Name, previousRelease.Version))
+	}
+	return fmt.Errorf("post-upgrade check [%s] failed: %w", check, checkErr)
+}
 
-	fmt.Printf("%v%v You have succesfully upgraded rancher from version [%s] to version [%s]!\n", emoji.PartyPopper, emoji.Fireworks, currentVersion, newRelease.Chart.Metadata.Version)
+// shellCommand runs command with the platform's shell, so checks can use pipes and quoting.
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
 
-	return nil
+// explainStep prints the explanation of the step about to run when --explain is set.
+func (u *UpgradeActionClient) explainStep(explanation i18n.Message) {
+	if !u.explain {
+		return
+	}
+	color.Cyan("\n%s\n", i18n.T(explanation))
+}
+
+func parseLabels(labelFlags []string) (map[string]string, error) {
+	if len(labelFlags) == 0 {
+		return nil, nil
+	}
+	labels := make(map[string]string, len(labelFlags))
+	for _, labelFlag := range labelFlags {
+		key, value, found := strings.Cut(labelFlag, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid label [%s], must be in key=value form", labelFlag)
+		}
+		labels[key] = value
+	}
+	return labels, nil
 }
 
-func chartValuesPrompt(chart *chart.Chart, values map[string]interface{}, reader *bufio.Reader) (map[string]interface{}, error) {
+func chartValuesPrompt(chart *chart.Chart, values map[string]interface{}, redactKeys []string, guided bool, reader *prompter) (map[string]interface{}, error) {
+	if guided && len(chart.Schema) == 0 {
+		color.Yellow("%s", i18n.T(i18n.GuidedValuesUnavailable, emoji.Warning))
+		guided = false
+	}
 	var done bool
 	for !done {
 		if len(values) != 0 {
-			valuesYAMLBytes, err := yaml.Marshal(values)
+			valuesYAMLBytes, err := renderValues(chart, values, false, "yaml", redactKeys)
 			if err != nil {
 				return nil, err
 			}
-			fmt.Println("Here are the current chart override values:")
+			fmt.Println(i18n.T(i18n.CurrentOverrideValues))
 			fmt.Printf("%s\n", string(valuesYAMLBytes))
 		} else {
-			fmt.Println("There are currently no chart override values configured.")
+			fmt.Println(i18n.T(i18n.NoOverrideValues))
+		}
+		if reader.assumeYes {
+			fmt.Println(i18n.T(i18n.KeepingOverrideValues))
+			return values, nil
 		}
 		answer := ""
 		var err error
 		// make this into a function for any y/n question
 		for answer == "" {
-			fmt.Print("Would you like to see all configured values, including defaults? [y/n]")
+			fmt.Print(i18n.T(i18n.ShowAllValuesPrompt))
 			answer, err = reader.ReadString('\n')
 			if err != nil {
 				return nil, err
@@ -171,25 +1685,28 @@ func chartValuesPrompt(chart *chart.Chart, values map[string]interface{}, reader
 			if answer == "n" || answer == "y" {
 				break
 			}
-			fmt.Println("\nInvalid input, try again.")
+			fmt.Printf("\n%s\n", i18n.T(i18n.InvalidInput))
 		}
 		if answer == "y" {
-			coalescedValues, err := chartutil.CoalesceValues(chart, values)
+			// showing the defaults is a convenience, a chart whose defaults do not coalesce can still be upgraded
+			// with the override values
+			coalescedValuesYAMLBytes, err := renderValues(chart, values, true, "yaml", redactKeys)
 			if err != nil {
-				return nil, err
-			}
-			coalescedValuesYAMLBytes, err := yaml.Marshal(coalescedValues)
-			if err != nil {
-				return nil, err
+				logrus.Debugf("coalescing the chart default values failed: %v", err)
+				color.Yellow("%s", i18n.T(i18n.CoalesceValuesFailed, emoji.Warning))
+			} else {
+				fmt.Println(i18n.T(i18n.ValuesToApply))
+				fmt.Println(string(coalescedValuesYAMLBytes))
 			}
-			fmt.Println("Values to be applied to rancher chart:")
-			fmt.Println(string(coalescedValuesYAMLBytes))
 		}
 		answer = ""
 		for answer == "" {
-			fmt.Println("\nSelect one of the following options by entering their corresponding number")
-			fmt.Println("1. Continue with displayed override chart values")
-			fmt.Println("2. Configure different override values")
+			fmt.Printf("\n%s\n", i18n.T(i18n.ValuesMenu))
+			fmt.Println(i18n.T(i18n.ValuesMenuContinue))
+			fmt.Println(i18n.T(i18n.ValuesMenuConfigure))
+			if guided {
+				fmt.Println(i18n.T(i18n.ValuesMenuGuided))
+			}
 			answer, err = reader.ReadString('\n')
 			if err != nil {
 				return nil, err
@@ -201,224 +1718,208 @@ func chartValuesPrompt(chart *chart.Chart, values map[string]interface{}, reader
 			}
 
 			if answer == "2" {
-				values, err = uploadValuesPrompt(reader)
+				uploadedValues, err := uploadValuesPrompt(reader)
+				if err != nil {
+					return nil, err
+				}
+				if uploadedValues != nil {
+					values = uploadedValues
+				}
+				continue
+			}
+
+			if answer == "3" && guided {
+				if values, err = guidedValuesPrompt(chart.Schema, values, redactKeys, reader); err != nil {
+					return nil, err
+				}
 				continue
 			}
-			fmt.Println("\nInvalid input, please try again.")
+			fmt.Printf("\n%s\n", i18n.T(i18n.InvalidInput))
 		}
 	}
 	return values, nil
 }
 
-func uploadValuesPrompt(reader *bufio.Reader) (map[string]interface{}, error) {
-	fmt.Printf("Enter a filepath for a values.yaml file: ")
-	filepath, err := reader.ReadString('\n')
-	if err != nil {
-		return nil, err
-	}
-	file, err := os.Open(filepath)
-	if err != nil {
-		return nil, err
-	}
-	data, err := io.ReadAll(file)
-	if err != nil {
-		return nil, err
+// renderValues marshals the override values, or the override values coalesced with the chart's defaults when all is
+// set, in the given output format. Keys matching redactKeys are masked in the output only.
+func renderValues(chart *chart.Chart, values map[string]interface{}, all bool, output string, redactKeys []string) ([]byte, error) {
+	if all {
+		coalescedValues, err := chartutil.CoalesceValues(chart, values)
+		if err != nil {
+			return nil, err
+		}
+		values = coalescedValues
 	}
-	var values map[string]interface{}
-	err = yaml.Unmarshal(data, &values)
-	if err != nil {
-		return nil, err
+	values = redactValues(values, redactKeys)
+
+	switch output {
+	case "yaml":
+		return yaml.Marshal(values)
+	case "json":
+		return json.MarshalIndent(values, "", "  ")
+	default:
+		return nil, fmt.Errorf("unsupported output format [%s], must be one of: yaml, json", output)
 	}
-	return values, nil
 }
 
-func promptForContinue(reader *bufio.Reader) (bool, error) {
-	var answer string
-	var err error
-	for answer == "" {
-		fmt.Print("Continue? [y/n]")
-		answer, err = reader.ReadString('\n')
-		if err != nil {
-			return false, err
-		}
-
-		answer = strings.ToLower(strings.TrimSpace(answer))
-		if answer == "n" || answer == "y" {
-			break
-		}
-		fmt.Println("\nInvalid input, try again.")
+// saveValuesRedactKeys returns the keys to mask in the --save-values file. The file seeds the next upgrade, so the
+// default --redact-keys are not applied, only keys passed explicitly are.
+func saveValuesRedactKeys(ctx *cli.Context) []string {
+	if !ctx.IsSet("redact-keys") {
+		return nil
 	}
-	return answer == "y", nil
+	return ctx.StringSlice("redact-keys")
 }
 
-func getReleasesBetweenInclusive(startingRelease, finalRelease string) ([]string, error) {
-	startingSemver, err := semver.New(startingRelease)
-	if err != nil {
-		return nil, err
+// saveValues writes the override values to path, as JSON for a .json path and as YAML otherwise. Keys matching
+// redactKeys are masked. The file is only readable by the user, the values may hold credentials.
+func saveValues(path string, values map[string]interface{}, redactKeys []string) error {
+	output := "yaml"
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		output = "json"
 	}
-	finalSemver, err := semver.New(finalRelease)
+	data, err := renderValues(nil, values, false, output, redactKeys)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	diff := finalSemver.Patch - startingSemver.Patch
-	releases := make([]string, diff+1)
-	for i := uint64(0); i < diff+1; i++ {
-		releases[i] = fmt.Sprintf("%d.%d.%d", startingSemver.Major, startingSemver.Minor, startingSemver.Patch+i)
+	if output == "json" {
+		data = append(data, '\n')
 	}
-	return releases, nil
+	return os.WriteFile(path, data, 0600)
 }
 
-func parseReleaseNotes(releases []string) ([][]string, [][]string, error) {
-	bugfixes := make([][]string, len(releases))
-	knownIssues := make([][]string, len(releases))
-
-	var recentBugfixAddition, recentKnownIssuesAddition string
-	lastReleaseBugfixes := ""
-	lastReleaseKnownIssues := ""
-	for index, release := range releases {
-		releaseNotes, err := getReleaseNotes(release)
-		if err != nil {
-			return nil, nil, err
-		}
+// maxValuesFileAttempts is how many times a values file path is asked for before giving up.
+const maxValuesFileAttempts = 3
 
-		releaseNotes = markdownCommentsReg.ReplaceAllString(releaseNotes, "")
+// uploadValuesPrompt asks for comma separated values files until all of them can be read and parsed, and merges them
+// in order with later files winning, same as helm's -f. An empty answer cancels and returns nil values, so the current
+// values are kept.
+func uploadValuesPrompt(reader *prompter) (map[string]interface{}, error) {
+	if reader.assumeYes {
+		return nil, fmt.Errorf("a values file cannot be entered when --yes is set")
+	}
 
-		fullBugfixBody, err := parseNotesSections(majorBugFixHeader, rancherBehaviorChangesHeader, releaseNotes)
+	for attempt := 1; ; attempt++ {
+		fmt.Print(i18n.T(i18n.EnterValuesFilePath))
+		path, err := reader.ReadString('\n')
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
-		if lastReleaseBugfixes != "" {
-			recentBugfixAddition = strings.Replace(fullBugfixBody, lastReleaseBugfixes, "", 1)
-		} else {
-			recentBugfixAddition = fullBugfixBody
+		path = strings.TrimSpace(path)
+		if path == "" {
+			return nil, nil
 		}
-		lastReleaseBugfixes = fullBugfixBody
-		bugfixes[index] = parseBulletPoints(recentBugfixAddition)
 
-		fullKnownIssuesBody, err := parseNotesSections(knownIssuesHeader, installUpgradeNotesHeader, releaseNotes)
-		if err != nil {
-			return nil, nil, err
+		values, err := readValuesFiles(strings.Split(path, ","))
+		if err == nil {
+			return values, nil
 		}
-		if lastReleaseKnownIssues != "" {
-			recentKnownIssuesAddition = strings.Replace(fullKnownIssuesBody, lastReleaseKnownIssues, "", 1)
-		} else {
-			recentKnownIssuesAddition = fullKnownIssuesBody
+		if attempt == maxValuesFileAttempts {
+			return nil, err
 		}
-		lastReleaseKnownIssues = fullKnownIssuesBody
-		knownIssues[index] = parseBulletPoints(recentKnownIssuesAddition)
+		color.Red("%s", err.Error())
+		fmt.Println(i18n.T(i18n.RetryValuesFile, maxValuesFileAttempts-attempt))
 	}
-	return bugfixes, knownIssues, nil
 }
 
-func walkthroughRelevantNotes(releases []string, bugfixes [][]string, knownIssues [][]string, reader *bufio.Reader) (bool, error) {
-	fmt.Printf("There have been %d releases between rancher [%s] and rancher [%s] (inclusive).\n", len(releases)-1, releases[0], releases[len(releases)-1])
-	fmt.Println("Let's go over the changes that have happened throughout these releases")
-	for index, release := range releases {
-		if index == len(release)-1 {
-			break
-		}
-		nextReleaseIndex := index + 1
-		fmt.Printf("%s -> %s\n", release, releases[nextReleaseIndex])
-		cont, err := displayBugFixes(releases[nextReleaseIndex], bugfixes[nextReleaseIndex], reader)
-		if err != nil {
-			return false, err
-		}
-		if !cont {
-			return false, nil
+// readValuesFiles checks that every file exists before deep merging them in order.
+func readValuesFiles(paths []string) (map[string]interface{}, error) {
+	for i, path := range paths {
+		paths[i] = strings.TrimSpace(path)
+		if _, err := os.Stat(paths[i]); err != nil {
+			return nil, err
 		}
-		cont, err = displayKnownIssues(releases[nextReleaseIndex], knownIssues[nextReleaseIndex], reader)
+	}
+
+	merged := map[string]interface{}{}
+	for _, path := range paths {
+		values, err := readValuesFile(path)
 		if err != nil {
-			return false, err
-		}
-		if !cont {
-			return false, nil
+			return nil, err
 		}
+		merged = mergeValues(merged, values)
 	}
-	return true, nil
+	if len(paths) > 1 {
+		fmt.Println(i18n.T(i18n.MergedValuesFiles, len(paths), strings.Join(paths, ", ")))
+	}
+	return merged, nil
 }
 
-func displayBugFixes(release string, bugfixes []string, reader *bufio.Reader) (bool, error) {
-	var displayedOpeningMessage bool
-
-	for _, bugfix := range bugfixes {
-		if bugfix == "" || bugfix == "-->" {
-			continue
-		}
-		if !displayedOpeningMessage {
-			color.Green("Here are some of the bugfixes introduced by release [%s]", release)
-			displayedOpeningMessage = true
+// mergeValues deep merges override into base, nested maps are merged and anything else in override replaces what is
+// in base. base is modified and returned.
+func mergeValues(base, override map[string]interface{}) map[string]interface{} {
+	for key, value := range override {
+		if overrideMap, ok := value.(map[string]interface{}); ok {
+			if baseMap, ok := base[key].(map[string]interface{}); ok {
+				base[key] = mergeValues(baseMap, overrideMap)
+				continue
+			}
 		}
-		fmt.Printf("%v %s\n", emoji.CheckMark, bugfix)
-	}
-	if !displayedOpeningMessage {
-		fmt.Println("We did not find any bugfixes, we recommend consulting the release page for more info.")
+		base[key] = value
 	}
-	fmt.Printf("If you would like to read more about bugfixes in release [%s], visit %sv%s\n", release, rancherReleaseNotesPrefix, release)
-	return promptForContinue(reader)
+	return base
 }
 
-func displayKnownIssues(release string, knownIssues []string, reader *bufio.Reader) (bool, error) {
-	var displayedOpeningMessage bool
-
-	for _, issue := range knownIssues {
-		if issue == "" || issue == "-->" {
-			continue
-		}
-		if !displayedOpeningMessage {
-			fmt.Printf("Let's review the known issues in release [%s]\n", release)
-			displayedOpeningMessage = true
-		}
-		fmt.Printf("%v  %s\n", emoji.RaisedHand, issue)
-		fmt.Printf("Continue if you acknowledge this issue and still wish to proceed. ")
-		cont, err := promptForContinue(reader)
-		if err != nil {
-			return false, err
-		}
-		if !cont {
-			return false, nil
+// copyValues returns a copy of values that mergeValues can merge into without changing values, nested maps are
+// copied and everything else is shared.
+func copyValues(values map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if nested, ok := value.(map[string]interface{}); ok {
+			value = copyValues(nested)
 		}
+		copied[key] = value
 	}
-	if !displayedOpeningMessage {
-		fmt.Printf("We did not find any known issues for release [%s].\n", release)
-	}
-	return true, nil
+	return copied
 }
 
-func getReleaseNotes(release string) (string, error) {
-	releaseURL := fmt.Sprintf("%sv%s", ghReleaseNotesAPIPrefix, release)
-	resp, err := http.Get(releaseURL)
+func readValuesFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse values file [%s]: %w", path, err)
 	}
+	return values, nil
+}
 
-	// parsing json is forgone here as it does not reduce the amount of processing needed
-	body := string(bodyBytes)
+// promptForConfirmPhrase asks the user to type phrase before the release is upgraded for real, so a habitual y cannot
+// upgrade the wrong cluster. It cannot be answered with --yes.
+func promptForConfirmPhrase(targetRelease *release.Release, phrase string, reader *prompter) (bool, error) {
+	if reader.assumeYes {
+		return false, fmt.Errorf("--require-confirm-phrase needs the phrase typed in and cannot be used with --yes or --interactive=false")
+	}
 
-	return body, nil
+	fmt.Print(i18n.T(i18n.ConfirmPhrasePrompt, targetRelease.Name, targetRelease.Namespace, phrase))
+	answer, err := reader.ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(answer) == phrase, nil
 }
 
-func parseNotesSections(header1, header2, notes string) (string, error) {
-	startIndex := strings.Index(notes, header1)
-	stopIndex := strings.Index(notes, header2)
-	if startIndex == -1 || stopIndex == -1 {
-		return "", nil
+func promptForContinue(reader *prompter) (bool, error) {
+	if reader.assumeYes {
+		fmt.Println(i18n.T(i18n.ContinuePrompt) + " y")
+		return true, nil
 	}
-	sectionBody := notes[strings.Index(notes, header1)+len(header1) : strings.Index(notes, header2)]
-	sectionBody = strings.ReplaceAll(sectionBody, "\\r\\n", "")
 
-	return sectionBody, nil
-}
+	var answer string
+	var err error
+	for answer == "" {
+		fmt.Print(i18n.T(i18n.ContinuePrompt))
+		answer, err = reader.ReadString('\n')
+		if err != nil {
+			return false, err
+		}
 
-func parseBulletPoints(section string) []string {
-	lines := strings.Split(section, "- ")
-	bullets := make([]string, 0)
-	for _, line := range lines {
-		bullets = append(bullets, line)
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer == "n" || answer == "y" {
+			break
+		}
+		fmt.Printf("\n%s\n", i18n.T(i18n.InvalidInput))
 	}
-	return bullets
+	return answer == "y", nil
 }
@@ -0,0 +1,283 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/rmweir/rancher-upgrader/internal/clock"
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestAppVersionDowngrade(t *testing.T) {
+	tests := []struct {
+		name      string
+		installed string
+		target    string
+		want      bool
+	}{
+		{name: "higher", installed: "v2.7.5", target: "v2.7.6", want: false},
+		{name: "equal", installed: "v2.7.5", target: "v2.7.5", want: false},
+		{name: "equal without v prefix", installed: "v2.7.5", target: "2.7.5", want: false},
+		{name: "lower patch", installed: "v2.7.5", target: "v2.7.4", want: true},
+		{name: "lower minor", installed: "v2.8.0", target: "v2.7.9", want: true},
+		{name: "lower prerelease", installed: "v2.7.5", target: "v2.7.5-rc1", want: true},
+		{name: "unparsable installed", installed: "latest", target: "v2.7.4", want: false},
+		{name: "unparsable target", installed: "v2.7.5", target: "head", want: false},
+		{name: "empty target", installed: "v2.7.5", target: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			installed := &chart.Metadata{Name: "rancher", Version: "2.7.5", AppVersion: tt.installed}
+			target := &chart.Metadata{Name: "rancher", Version: "2.7.6", AppVersion: tt.target}
+			if got := appVersionDowngrade(installed, target); got != tt.want {
+				t.Errorf("appVersionDowngrade(%q, %q) = %t, want %t", tt.installed, tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfirmAppVersionDowngrade(t *testing.T) {
+	installed := &chart.Metadata{Name: "rancher", Version: "2.7.5", AppVersion: "v2.7.5"}
+	// the chart version rose but the appVersion fell
+	mispackaged := &chart.Metadata{Name: "rancher", Version: "2.7.6", AppVersion: "v2.7.4"}
+	upgrade := &chart.Metadata{Name: "rancher", Version: "2.7.6", AppVersion: "v2.7.6"}
+
+	tests := []struct {
+		name           string
+		target         *chart.Metadata
+		input          string
+		assumeYes      bool
+		allowDowngrade bool
+		want           bool
+		wantErr        bool
+	}{
+		{name: "downgrade confirmed", target: mispackaged, input: "y\n", want: true},
+		{name: "downgrade declined", target: mispackaged, input: "n\n", want: false},
+		{name: "unattended downgrade is refused", target: mispackaged, assumeYes: true, wantErr: true},
+		{name: "unattended downgrade allowed", target: mispackaged, assumeYes: true, allowDowngrade: true, want: true},
+		{name: "allowed downgrade is not asked", target: mispackaged, input: "", allowDowngrade: true, want: true},
+		{name: "unattended upgrade without a downgrade", target: upgrade, assumeYes: true, want: true},
+		{name: "downgrade without an answer", target: mispackaged, input: "", wantErr: true},
+		{name: "no downgrade is not asked", target: upgrade, input: "", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := confirmAppVersionDowngrade(installed, tt.target, tt.allowDowngrade, newPrompter(strings.NewReader(tt.input), tt.assumeYes))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("confirmAppVersionDowngrade() expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("confirmAppVersionDowngrade() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("confirmAppVersionDowngrade() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+// historyExecer serves release histories by namespace and name, everything else is left unimplemented.
+type historyExecer struct {
+	helmExecer
+	histories map[string][]*release.Release
+	err       error
+}
+
+func (h historyExecer) History(namespace, releaseName string) ([]*release.Release, error) {
+	return h.histories[namespace+"/"+releaseName], h.err
+}
+
+func testRevision(version int, chartVersion string, status release.Status) *release.Release {
+	revision := testRelease("rancher", "cattle-system", chartVersion)
+	revision.Version = version
+	revision.Info.Status = status
+	return revision
+}
+
+func TestUpgradedMeanwhile(t *testing.T) {
+	current := testRevision(3, "2.7.5", release.StatusDeployed)
+	superseded := testRevision(3, "2.7.5", release.StatusSuperseded)
+
+	tests := []struct {
+		name      string
+		histories map[string][]*release.Release
+		err       error
+		want      bool
+		wantErr   bool
+	}{
+		{
+			name:      "re-run after the upgrade",
+			histories: map[string][]*release.Release{"cattle-system/rancher": {superseded, testRevision(4, "2.7.6", release.StatusDeployed)}},
+			want:      true,
+		},
+		{
+			name:      "not upgraded yet",
+			histories: map[string][]*release.Release{"cattle-system/rancher": {testRevision(2, "2.7.4", release.StatusSuperseded), current}},
+			want:      false,
+		},
+		{
+			name:      "upgraded to another version",
+			histories: map[string][]*release.Release{"cattle-system/rancher": {superseded, testRevision(4, "2.7.7", release.StatusDeployed)}},
+			want:      false,
+		},
+		{
+			name:      "upgrade to the version failed",
+			histories: map[string][]*release.Release{"cattle-system/rancher": {superseded, testRevision(4, "2.7.6", release.StatusFailed)}},
+			want:      false,
+		},
+		{
+			name:      "upgrade in progress",
+			histories: map[string][]*release.Release{"cattle-system/rancher": {superseded, testRevision(4, "2.7.6", release.StatusPendingUpgrade)}},
+			want:      false,
+		},
+		{
+			name: "release of the same name upgraded in another namespace",
+			histories: map[string][]*release.Release{
+				"cattle-system/rancher": {current},
+				"rancher-test/rancher":  {testRevision(3, "2.7.5", release.StatusSuperseded), testRevision(4, "2.7.6", release.StatusDeployed)},
+			},
+			want: false,
+		},
+		{name: "no history", histories: map[string][]*release.Release{}, want: false},
+		{name: "history unavailable", err: errors.New("forbidden"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &UpgradeActionClient{helmExecer: historyExecer{histories: tt.histories, err: tt.err}}
+			got, err := u.upgradedMeanwhile(current, "2.7.6")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("upgradedMeanwhile() expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("upgradedMeanwhile() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("upgradedMeanwhile() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+// captureOutput returns what f prints to stdout, including the colored output.
+func captureOutput(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, colorOutput := os.Stdout, color.Output
+	os.Stdout, color.Output = w, w
+	defer func() {
+		os.Stdout, color.Output = stdout, colorOutput
+	}()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+	f()
+	w.Close()
+	return <-output
+}
+
+func TestChartValuesPromptCoalesceFailure(t *testing.T) {
+	targetChart := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "rancher", Version: "2.7.6"},
+		Values:   map[string]interface{}{"replicas": 3},
+	}
+	targetChart.AddDependency(&chart.Chart{Metadata: &chart.Metadata{Name: "webhook", Version: "2.0.6"}})
+	// the values of a subchart must be a table, so coalescing them with the chart's defaults fails
+	values := map[string]interface{}{"hostname": "rancher.example.com", "webhook": "enabled"}
+
+	var got map[string]interface{}
+	var err error
+	output := captureOutput(t, func() {
+		// show all values, then continue with the override values
+		got, err = chartValuesPrompt(targetChart, values, nil, false, newPrompter(strings.NewReader("y\n1\n"), false))
+	})
+	if err != nil {
+		t.Fatalf("chartValuesPrompt() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, values) {
+		t.Errorf("chartValuesPrompt() = %v, want the override values %v", got, values)
+	}
+	if warning := i18n.T(i18n.CoalesceValuesFailed, ""); !strings.Contains(output, strings.TrimSpace(warning)) {
+		t.Errorf("chartValuesPrompt() output does not warn that the default values cannot be shown:\n%s", output)
+	}
+	if strings.Contains(output, i18n.T(i18n.ValuesToApply)) {
+		t.Errorf("chartValuesPrompt() output shows the values to apply although they could not be coalesced:\n%s", output)
+	}
+}
+
+func TestUpgradeRancherKubeconfigFromStdin(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "prompting", args: []string{"--kubeconfig", "-"}},
+		{name: "prompting explicitly", args: []string{"--kubeconfig", "-", "--yes=false", "--interactive=true"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &UpgradeActionClient{clock: clock.Real{}}
+			err := u.UpgradeRancher(commandContext(t, UpgradeCommand(), tt.args...))
+			want := "--kubeconfig - needs --yes or --interactive=false, stdin is needed to answer the upgrade's prompts"
+			if err == nil || err.Error() != want {
+				t.Errorf("UpgradeRancher() error = %v, want %q", err, want)
+			}
+		})
+	}
+}
+
+func TestSaveValuesRedactKeys(t *testing.T) {
+	values := map[string]interface{}{"hostname": "rancher.example.com", "bootstrapPassword": "admin-secret", "auditLog": map[string]interface{}{"token": "audit-secret"}}
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "default keys are not applied",
+			want: "auditLog:\n  token: audit-secret\nbootstrapPassword: admin-secret\nhostname: rancher.example.com\n",
+		},
+		{
+			name: "passed keys are masked",
+			args: []string{"--redact-keys", "bootstrapPassword"},
+			want: "auditLog:\n  token: audit-secret\nbootstrapPassword: '***'\nhostname: rancher.example.com\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "values.yaml")
+			ctx := commandContext(t, UpgradeCommand(), tt.args...)
+			if err := saveValues(path, values, saveValuesRedactKeys(ctx)); err != nil {
+				t.Fatalf("saveValues() unexpected error: %v", err)
+			}
+			saved, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(saved) != tt.want {
+				t.Errorf("saved values =\n%s\nwant\n%s", saved, tt.want)
+			}
+		})
+	}
+}
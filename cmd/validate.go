@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/enescakir/emoji"
+	"github.com/fatih/color"
+	"github.com/rmweir/rancher-upgrader/internal/helm"
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
+	"github.com/urfave/cli/v2"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+type ValidateActionClient struct{}
+
+func ValidateCommand() *cli.Command {
+	flags := append(clusterFlags(), repoFlags()...)
+	flags = append(flags, &cli.BoolFlag{
+		Name:  "stay-on-minor",
+		Usage: "Only resolve a newer patch of the installed minor version as the target",
+	}, upgradePolicyFlag())
+
+	c := &ValidateActionClient{}
+	return &cli.Command{
+		Name:   "validate",
+		Usage:  "Check that rancher is ready to upgrade without fetching release notes or upgrading",
+		Action: c.Validate,
+		Flags:  flags,
+	}
+}
+
+// preflightCheck is a single line of the validate checklist. It is skipped when a check it requires did not pass.
+type preflightCheck struct {
+	name     i18n.Message
+	requires []i18n.Message
+	run      func() (string, error)
+}
+
+func (v *ValidateActionClient) Validate(ctx *cli.Context) error {
+	opts := clientOptions(ctx)
+	var releaseClient, chartClient helm.Client
+	var rancherRelease *release.Release
+	// a kubeconfig from stdin or --kubeconfig-data lives in a temporary file that the later checks still read
+	cleanupKubeconfig := func() {}
+	defer func() { cleanupKubeconfig() }()
+
+	checks := []preflightCheck{
+		{
+			name: i18n.CheckKubeconfig,
+			run: func() (string, error) {
+				kubeconfigPath, cleanup, err := resolveKubeconfig(ctx, os.Stdin)
+				if err != nil {
+					return "", err
+				}
+				cleanupKubeconfig = cleanup
+				opts.KubeconfigPath = kubeconfigPath
+				if releaseClient, err = helm.NewReleaseClient(opts); err != nil {
+					return "", err
+				}
+				if err := releaseClient.VerifyKubeconfig(); err != nil {
+					return "", err
+				}
+				return kubeconfigPath, nil
+			},
+		},
+		{
+			name:     i18n.CheckCluster,
+			requires: []i18n.Message{i18n.CheckKubeconfig},
+			run: func() (string, error) {
+				return releaseClient.ServerVersion()
+			},
+		},
+		{
+			name:     i18n.CheckRelease,
+			requires: []i18n.Message{i18n.CheckCluster},
+			run: func() (string, error) {
+				selected, err := findRancherRelease(ctx, releaseClient, false, nil, os.Stdout)
+				if err != nil {
+					return "", err
+				}
+				rancherRelease = selected.Release
+				return fmt.Sprintf("%s:%s at %s", rancherRelease.Name, rancherRelease.Namespace, formatChartVersion(rancherRelease.Chart.Metadata)), nil
+			},
+		},
+		{
+			name: i18n.CheckRepo,
+			run: func() (string, error) {
+				var err error
+				if chartClient, err = helm.NewChartClient(opts); err != nil {
+					var missingRepo *helm.MissingRepoError
+					if errors.As(err, &missingRepo) {
+						return "", fmt.Errorf("%w, add it with: %s", err, missingRepo.Remediation())
+					}
+					return "", err
+				}
+				if opts.OCIRepo != "" {
+					return opts.OCIRepo, nil
+				}
+				return "rancher-stable", nil
+			},
+		},
+		{
+			name:     i18n.CheckTarget,
+			requires: []i18n.Message{i18n.CheckRelease, i18n.CheckRepo},
+			run: func() (string, error) {
+				currentVersion := rancherRelease.Chart.Metadata.Version
+				nextVersion, err := chartClient.GetNextSupportedRancherChartVersion(currentVersion)
+				if err != nil {
+					return "", err
+				}
+				if nextVersion == currentVersion {
+					return fmt.Sprintf("%s is the newest supported version", currentVersion), nil
+				}
+				return fmt.Sprintf("%s -> %s", currentVersion, nextVersion), nil
+			},
+		},
+	}
+
+	fmt.Println(i18n.T(i18n.ValidateHeader))
+	passed := make(map[i18n.Message]bool, len(checks))
+	failed := 0
+	for _, check := range checks {
+		var missing i18n.Message
+		for _, required := range check.requires {
+			if !passed[required] {
+				missing = required
+				break
+			}
+		}
+		if missing != "" {
+			failed++
+			color.Yellow("%s", i18n.T(i18n.CheckSkipped, emoji.WhiteQuestionMark, i18n.T(check.name), i18n.T(i18n.CheckDependsOn, i18n.T(missing))))
+			continue
+		}
+
+		detail, err := check.run()
+		if err != nil {
+			failed++
+			color.Red("%s", i18n.T(i18n.CheckFailed, emoji.CrossMark, i18n.T(check.name), err))
+			continue
+		}
+		passed[check.name] = true
+		color.Green("%s", i18n.T(i18n.CheckPassed, emoji.CheckMarkButton, i18n.T(check.name), detail))
+	}
+
+	if failed != 0 {
+		return cli.Exit(i18n.T(i18n.ValidateFailed, emoji.StopSign, failed, len(checks)), 1)
+	}
+	fmt.Println(i18n.T(i18n.ValidatePassed, emoji.PartyingFace, len(checks)))
+	return nil
+}
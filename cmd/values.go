@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rmweir/rancher-upgrader/internal/helm"
+	"github.com/urfave/cli/v2"
+)
+
+type ValuesActionClient struct {
+	helmExecer helmExecer
+}
+
+func ValuesCommand() *cli.Command {
+	flags := append(clusterFlags(),
+		&cli.BoolFlag{
+			Name:  "all",
+			Usage: "Show all configured values, including chart defaults",
+		},
+		&cli.StringFlag{
+			Name:  "output",
+			Usage: "Output format, one of: yaml, json",
+			Value: "yaml",
+		},
+		redactKeysFlag(),
+	)
+
+	c := &ValuesActionClient{}
+	return &cli.Command{
+		Name:   "values",
+		Usage:  "Show the values of the installed rancher release",
+		Action: c.ShowValues,
+		Flags:  flags,
+	}
+}
+
+func (v *ValuesActionClient) Init(opts helm.Options) error {
+	client, err := helm.NewReleaseClient(opts)
+	if err != nil {
+		return err
+	}
+	v.helmExecer = client
+	return nil
+}
+
+func (v *ValuesActionClient) ShowValues(ctx *cli.Context) error {
+	kubeconfigPath, cleanup, err := resolveKubeconfig(ctx, os.Stdin)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	opts := clientOptions(ctx)
+	opts.KubeconfigPath = kubeconfigPath
+	if err := v.Init(opts); err != nil {
+		return err
+	}
+
+	// prompts go to stderr so the values can be piped
+	selected, err := findRancherRelease(ctx, v.helmExecer, isInteractive(), newPrompter(os.Stdin, false), os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	valuesBytes, err := renderValues(selected.Release.Chart, selected.Release.Config, ctx.Bool("all"), ctx.String("output"), ctx.StringSlice("redact-keys"))
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(valuesBytes))
+	return nil
+}
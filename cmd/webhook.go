@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/blang/semver/v4"
+	"github.com/enescakir/emoji"
+	"github.com/fatih/color"
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
+)
+
+// webhookChartName is the chart rancher installs its admission webhook from.
+const webhookChartName = "rancher-webhook"
+
+// checkWebhook warns when the installed rancher-webhook works with neither currentVersion nor targetVersion of rancher.
+// A webhook matching the installed rancher is expected, rancher upgrades it to a matching version itself once it is
+// upgraded. With enforce set, a mismatch is an error instead of a warning.
+func (u *UpgradeActionClient) checkWebhook(currentVersion, targetVersion string, enforce bool) error {
+	targetRange, targetRequirement, err := u.support.Webhook(targetVersion)
+	if err != nil || targetRequirement == "" {
+		return err
+	}
+
+	releases, err := u.helmExecer.ListReleases()
+	if err != nil {
+		return err
+	}
+	var webhookVersion string
+	for _, rel := range releases {
+		if rel.Chart != nil && rel.Chart.Metadata != nil && rel.Chart.Metadata.Name == webhookChartName {
+			webhookVersion = rel.Chart.Metadata.Version
+			break
+		}
+	}
+	if webhookVersion == "" {
+		fmt.Println(i18n.T(i18n.WebhookNotFound, webhookChartName))
+		return nil
+	}
+
+	installed, err := semver.Parse(webhookVersion)
+	if err != nil {
+		return fmt.Errorf("installed %s chart version [%s] is not a semantic version: %w", webhookChartName, webhookVersion, err)
+	}
+	if targetRange(installed) {
+		return nil
+	}
+	currentRange, currentRequirement, err := u.support.Webhook(currentVersion)
+	if err != nil {
+		return err
+	}
+	if currentRequirement != "" && currentRange(installed) {
+		fmt.Println(i18n.T(i18n.WebhookUpgradedByRancher, webhookChartName, webhookVersion, targetVersion, targetRequirement))
+		return nil
+	}
+
+	if enforce {
+		return fmt.Errorf("installed %s [%s] does not work with rancher [%s], which needs %s, fix the webhook or re-run without --enforce-webhook-compat",
+			webhookChartName, webhookVersion, targetVersion, targetRequirement)
+	}
+	color.Yellow("%s", i18n.T(i18n.WebhookMismatch, emoji.Warning, webhookChartName, webhookVersion, currentVersion, targetVersion, targetRequirement))
+	return nil
+}
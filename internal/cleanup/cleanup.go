@@ -0,0 +1,73 @@
+// Package cleanup removes temporary files and releases locks when the process is interrupted. Deferred calls do not
+// run when a signal terminates the process, so everything that must not outlive a run is registered here as well.
+package cleanup
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+)
+
+var (
+	mu      sync.Mutex
+	nextID  int
+	pending = map[int]func(){}
+)
+
+// Register adds f to the functions run on interrupt and returns a function that runs f and unregisters it, meant to
+// be deferred. f runs at most once, whichever comes first.
+func Register(f func()) func() {
+	mu.Lock()
+	id := nextID
+	nextID++
+	pending[id] = f
+	mu.Unlock()
+
+	return func() {
+		mu.Lock()
+		registered, ok := pending[id]
+		delete(pending, id)
+		mu.Unlock()
+		if ok {
+			registered()
+		}
+	}
+}
+
+// HandleSignals runs every registered function once SIGINT or SIGTERM is received, printing message first, and then
+// calls exit, os.Exit outside of tests, with the conventional 128+signal status.
+func HandleSignals(message string, exit func(code int)) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		received := <-signals
+		fmt.Fprintf(os.Stderr, "\n%s\n", message)
+		run()
+
+		code := 130
+		if received == syscall.SIGTERM {
+			code = 143
+		}
+		exit(code)
+	}()
+}
+
+// run calls the registered functions, most recently registered first, the same order deferred calls would run in.
+func run() {
+	mu.Lock()
+	ids := make([]int, 0, len(pending))
+	for id := range pending {
+		ids = append(ids, id)
+	}
+	funcs := pending
+	pending = map[int]func(){}
+	mu.Unlock()
+
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+	for _, id := range ids {
+		funcs[id]()
+	}
+}
@@ -0,0 +1,29 @@
+package cleanup
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	var calls []string
+	record := func(name string) func() {
+		return func() { calls = append(calls, name) }
+	}
+
+	Register(record("first"))
+	doneSecond := Register(record("second"))
+	Register(record("third"))
+	doneFourth := Register(record("fourth"))
+
+	// cleanups that already ran deferred are not run again on interrupt
+	doneSecond()
+	run()
+	// nor are they run again by their deferred call or a second interrupt
+	doneFourth()
+	run()
+
+	if got, want := strings.Join(calls, ","), "second,fourth,third,first"; got != want {
+		t.Errorf("cleanups ran as %s, want %s", got, want)
+	}
+}
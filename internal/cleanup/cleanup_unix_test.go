@@ -0,0 +1,33 @@
+//go:build !windows
+
+package cleanup
+
+import (
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleSignals(t *testing.T) {
+	var runs int32
+	Register(func() { atomic.AddInt32(&runs, 1) })
+
+	exited := make(chan int, 1)
+	HandleSignals("cleaning up...", func(code int) { exited <- code })
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGINT); err != nil {
+		t.Fatalf("failed to interrupt the test process: %v", err)
+	}
+
+	select {
+	case code := <-exited:
+		if code != 130 {
+			t.Errorf("exited with status %d after SIGINT, want 130", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGINT did not run the cleanups and exit")
+	}
+	if got := atomic.LoadInt32(&runs); got != 1 {
+		t.Errorf("registered cleanup ran %d times, want once", got)
+	}
+}
@@ -0,0 +1,30 @@
+// Package clock abstracts reading the time and waiting, so time dependent behavior such as cache expiry, retry
+// backoff and report timestamps can be driven by a fixed clock in tests.
+package clock
+
+import "time"
+
+// Clock tells the time and waits.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// Real is the Clock of the system.
+type Real struct{}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+func (Real) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// OrReal returns c, or Real when c is nil.
+func OrReal(c Clock) Clock {
+	if c == nil {
+		return Real{}
+	}
+	return c
+}
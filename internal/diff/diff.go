@@ -0,0 +1,173 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+type ChangeType string
+
+const (
+	Added    ChangeType = "added"
+	Removed  ChangeType = "removed"
+	Modified ChangeType = "modified"
+)
+
+// Change is a single field-level difference. Path is a dotted path into the document, with list indexes in brackets.
+type Change struct {
+	Type ChangeType  `json:"type"`
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// ResourceChange describes how a single kubernetes resource differs between two rendered manifests. Old and New are
+// the resource's documents, nil on the side it is missing from.
+type ResourceChange struct {
+	Type     ChangeType             `json:"type"`
+	Resource Resource               `json:"resource"`
+	Changes  []Change               `json:"changes,omitempty"`
+	Old      map[string]interface{} `json:"-"`
+	New      map[string]interface{} `json:"-"`
+}
+
+type Resource struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+func (r Resource) String() string {
+	if r.Namespace == "" {
+		return fmt.Sprintf("%s %s", r.Kind, r.Name)
+	}
+	return fmt.Sprintf("%s %s/%s", r.Kind, r.Namespace, r.Name)
+}
+
+// Values returns every leaf-level difference between old and new, sorted by path.
+func Values(old, new map[string]interface{}) []Change {
+	var changes []Change
+	diffValue("", old, new, &changes)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// Manifests compares two rendered helm manifests resource by resource. Resources are matched on kind, namespace, and
+// name. Unchanged resources are omitted from the result.
+func Manifests(old, new string) ([]ResourceChange, error) {
+	oldResources, err := ParseManifest(old)
+	if err != nil {
+		return nil, err
+	}
+	newResources, err := ParseManifest(new)
+	if err != nil {
+		return nil, err
+	}
+
+	var resourceChanges []ResourceChange
+	for resource, oldDoc := range oldResources {
+		newDoc, ok := newResources[resource]
+		if !ok {
+			resourceChanges = append(resourceChanges, ResourceChange{Type: Removed, Resource: resource, Old: oldDoc})
+			continue
+		}
+		if changes := Values(oldDoc, newDoc); len(changes) != 0 {
+			resourceChanges = append(resourceChanges, ResourceChange{Type: Modified, Resource: resource, Changes: changes, Old: oldDoc, New: newDoc})
+		}
+	}
+	for resource, newDoc := range newResources {
+		if _, ok := oldResources[resource]; !ok {
+			resourceChanges = append(resourceChanges, ResourceChange{Type: Added, Resource: resource, New: newDoc})
+		}
+	}
+
+	sort.Slice(resourceChanges, func(i, j int) bool {
+		return resourceChanges[i].Resource.String() < resourceChanges[j].Resource.String()
+	})
+	return resourceChanges, nil
+}
+
+// ParseManifest returns the documents of a rendered manifest keyed by the resource they describe.
+func ParseManifest(manifest string) (map[Resource]map[string]interface{}, error) {
+	resources := make(map[Resource]map[string]interface{})
+	for _, content := range releaseutil.SplitManifests(manifest) {
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+			return nil, err
+		}
+		if len(doc) == 0 {
+			continue
+		}
+		resources[resourceFor(doc)] = doc
+	}
+	return resources, nil
+}
+
+func resourceFor(doc map[string]interface{}) Resource {
+	resource := Resource{}
+	resource.Kind, _ = doc["kind"].(string)
+	if metadata, ok := doc["metadata"].(map[string]interface{}); ok {
+		resource.Name, _ = metadata["name"].(string)
+		resource.Namespace, _ = metadata["namespace"].(string)
+	}
+	return resource
+}
+
+func diffValue(path string, old, new interface{}, changes *[]Change) {
+	oldMap, oldIsMap := old.(map[string]interface{})
+	newMap, newIsMap := new.(map[string]interface{})
+	if oldIsMap && newIsMap {
+		for key, oldChild := range oldMap {
+			newChild, ok := newMap[key]
+			if !ok {
+				*changes = append(*changes, Change{Type: Removed, Path: joinPath(path, key), Old: oldChild})
+				continue
+			}
+			diffValue(joinPath(path, key), oldChild, newChild, changes)
+		}
+		for key, newChild := range newMap {
+			if _, ok := oldMap[key]; !ok {
+				*changes = append(*changes, Change{Type: Added, Path: joinPath(path, key), New: newChild})
+			}
+		}
+		return
+	}
+
+	oldList, oldIsList := old.([]interface{})
+	newList, newIsList := new.([]interface{})
+	if oldIsList && newIsList {
+		for i := 0; i < len(oldList) || i < len(newList); i++ {
+			indexPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(newList):
+				*changes = append(*changes, Change{Type: Removed, Path: indexPath, Old: oldList[i]})
+			case i >= len(oldList):
+				*changes = append(*changes, Change{Type: Added, Path: indexPath, New: newList[i]})
+			default:
+				diffValue(indexPath, oldList[i], newList[i], changes)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(old, new) {
+		*changes = append(*changes, Change{Type: Modified, Path: path, Old: old, New: new})
+	}
+}
+
+func joinPath(path, key string) string {
+	if strings.ContainsAny(key, ".[]") {
+		return fmt.Sprintf("%s[%q]", path, key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,115 @@
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestValues(t *testing.T) {
+	old := map[string]interface{}{
+		"hostname": "rancher.example.com",
+		"replicas": 3,
+		"ingress":  map[string]interface{}{"tls": map[string]interface{}{"source": "rancher"}},
+		"extraEnv": []interface{}{"A=1", "B=2"},
+		"auditLog": map[string]interface{}{"level": 1},
+		"a.b":      "dotted",
+	}
+	new := map[string]interface{}{
+		"hostname": "rancher.example.com",
+		"replicas": 5,
+		"ingress":  map[string]interface{}{"tls": map[string]interface{}{"source": "secret"}},
+		"extraEnv": []interface{}{"A=1", "B=3", "C=4"},
+		"debug":    true,
+		"a.b":      "dotted too",
+	}
+
+	// keys holding dots are quoted, and sort before the others
+	want := []Change{
+		{Type: Modified, Path: `["a.b"]`, Old: "dotted", New: "dotted too"},
+		{Type: Removed, Path: "auditLog", Old: map[string]interface{}{"level": 1}},
+		{Type: Added, Path: "debug", New: true},
+		{Type: Modified, Path: "extraEnv[1]", Old: "B=2", New: "B=3"},
+		{Type: Added, Path: "extraEnv[2]", New: "C=4"},
+		{Type: Modified, Path: "ingress.tls.source", Old: "rancher", New: "secret"},
+		{Type: Modified, Path: "replicas", Old: 3, New: 5},
+	}
+	if got := Values(old, new); !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() =\n%v\nwant\n%v", got, want)
+	}
+	if got := Values(old, old); len(got) != 0 {
+		t.Errorf("Values() of equal values = %v, want none", got)
+	}
+}
+
+func TestManifests(t *testing.T) {
+	old := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: cattle-system
+data:
+  level: "1"
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: rancher
+  namespace: cattle-system
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: legacy
+  namespace: cattle-system
+`
+	new := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: cattle-system
+data:
+  level: "2"
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: rancher
+  namespace: cattle-system
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: rancher
+`
+
+	resourceChanges, err := Manifests(old, new)
+	if err != nil {
+		t.Fatalf("Manifests() unexpected error: %v", err)
+	}
+	var got []string
+	for _, resourceChange := range resourceChanges {
+		got = append(got, string(resourceChange.Type)+" "+resourceChange.Resource.String())
+	}
+	want := []string{"added ClusterRole rancher", "modified ConfigMap cattle-system/settings", "removed Service cattle-system/legacy"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Manifests() = %v, want %v", got, want)
+	}
+
+	// the json output of --diff-format=json
+	data, err := json.Marshal(resourceChanges[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantJSON := `{"type":"modified","resource":{"kind":"ConfigMap","namespace":"cattle-system","name":"settings"},` +
+		`"changes":[{"type":"modified","path":"data.level","old":"1","new":"2"}]}`
+	if string(data) != wantJSON {
+		t.Errorf("json of the ConfigMap change = %s, want %s", data, wantJSON)
+	}
+
+	if _, err := Manifests("kind: [", ""); err == nil {
+		t.Error("Manifests() of an invalid manifest expected an error")
+	}
+}
@@ -0,0 +1,145 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// edit is a single line of a line-based diff: ' ' for an unchanged line, '-' for a removed and '+' for an added one.
+type edit struct {
+	kind byte
+	line string
+}
+
+// Unified returns a unified diff of the lines of old and new with context unchanged lines around each change, the same
+// as diff -U. It is empty when old and new are equal.
+func Unified(oldName, newName, old, new string, context int) string {
+	edits := lineEdits(splitLines(old), splitLines(new))
+
+	var changed []int
+	for i, e := range edits {
+		if e.kind != ' ' {
+			changed = append(changed, i)
+		}
+	}
+	if len(changed) == 0 {
+		return ""
+	}
+	if context < 0 {
+		context = 0
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+	for first := 0; first < len(changed); {
+		// changes separated by at most twice the context share a hunk, as their context lines would overlap or touch
+		last := first
+		for last+1 < len(changed) && changed[last+1]-changed[last]-1 <= 2*context {
+			last++
+		}
+		start, end := changed[first]-context, changed[last]+context+1
+		if start < 0 {
+			start = 0
+		}
+		if end > len(edits) {
+			end = len(edits)
+		}
+		writeHunk(&out, edits, start, end)
+		first = last + 1
+	}
+	return out.String()
+}
+
+func writeHunk(out *strings.Builder, edits []edit, start, end int) {
+	oldStart, newStart := 1, 1
+	for _, e := range edits[:start] {
+		if e.kind != '+' {
+			oldStart++
+		}
+		if e.kind != '-' {
+			newStart++
+		}
+	}
+	var oldCount, newCount int
+	for _, e := range edits[start:end] {
+		if e.kind != '+' {
+			oldCount++
+		}
+		if e.kind != '-' {
+			newCount++
+		}
+	}
+	// an empty side starts at the line before the hunk, as in diff -U
+	if oldCount == 0 {
+		oldStart--
+	}
+	if newCount == 0 {
+		newStart--
+	}
+
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+	for _, e := range edits[start:end] {
+		out.WriteByte(e.kind)
+		out.WriteString(e.line)
+		out.WriteByte('\n')
+	}
+}
+
+// hunkRange formats a side of a hunk header, leaving out the count of a single line as diff -U does.
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// lineEdits turns old into new with the fewest removed and added lines, using their longest common subsequence.
+func lineEdits(old, new []string) []edit {
+	common := make([][]int, len(old)+1)
+	for i := range common {
+		common[i] = make([]int, len(new)+1)
+	}
+	for i := len(old) - 1; i >= 0; i-- {
+		for j := len(new) - 1; j >= 0; j-- {
+			if old[i] == new[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	var edits []edit
+	i, j := 0, 0
+	for i < len(old) && j < len(new) {
+		switch {
+		case old[i] == new[j]:
+			edits = append(edits, edit{kind: ' ', line: old[i]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			edits = append(edits, edit{kind: '-', line: old[i]})
+			i++
+		default:
+			edits = append(edits, edit{kind: '+', line: new[j]})
+			j++
+		}
+	}
+	for ; i < len(old); i++ {
+		edits = append(edits, edit{kind: '-', line: old[i]})
+	}
+	for ; j < len(new); j++ {
+		edits = append(edits, edit{kind: '+', line: new[j]})
+	}
+	return edits
+}
+
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,72 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+// lines turns space separated words into a file with one word per line.
+func lines(words string) string {
+	if words == "" {
+		return ""
+	}
+	return strings.Join(strings.Fields(words), "\n") + "\n"
+}
+
+func TestUnified(t *testing.T) {
+	// every want is the output of diff -U<context> --label old --label new
+	tests := []struct {
+		name     string
+		old, new string
+		context  int
+		want     string
+	}{
+		{name: "equal", old: "a b c", new: "a b c", context: 3, want: ""},
+		{
+			name: "one change", old: "a b c d e f g h i j", new: "a b c d E f g h i j", context: 3,
+			want: "--- old\n+++ new\n@@ -2,7 +2,7 @@\n b\n c\n d\n-e\n+E\n f\n g\n h\n",
+		},
+		{
+			name: "changes twice the context apart share a hunk", old: "a b c d e f g h i j", new: "A b c d e f g H i j", context: 3,
+			want: "--- old\n+++ new\n@@ -1,10 +1,10 @@\n-a\n+A\n b\n c\n d\n e\n f\n g\n-h\n+H\n i\n j\n",
+		},
+		{
+			name: "changes further apart get their own hunks", old: "a b c d e f g h i j", new: "A b c d e f g h I j", context: 3,
+			want: "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-a\n+A\n b\n c\n d\n@@ -6,5 +6,5 @@\n f\n g\n h\n-i\n+I\n j\n",
+		},
+		{
+			name: "context 0", old: "a b c d e f g", new: "a B c d f g x", context: 0,
+			want: "--- old\n+++ new\n@@ -2 +2 @@\n-b\n+B\n@@ -5 +4,0 @@\n-e\n@@ -7,0 +7 @@\n+x\n",
+		},
+		{
+			name: "negative context is 0", old: "a b c", new: "a B c", context: -1,
+			want: "--- old\n+++ new\n@@ -2 +2 @@\n-b\n+B\n",
+		},
+		{
+			name: "change on the first line", old: "a b c d e f g", new: "A b c d e f g", context: 2,
+			want: "--- old\n+++ new\n@@ -1,3 +1,3 @@\n-a\n+A\n b\n c\n",
+		},
+		{
+			name: "change on the last line", old: "a b c d e f g", new: "a b c d e f G", context: 2,
+			want: "--- old\n+++ new\n@@ -5,3 +5,3 @@\n e\n f\n-g\n+G\n",
+		},
+		{
+			name: "insertion at the start without context", old: "a b c", new: "x a b c", context: 0,
+			want: "--- old\n+++ new\n@@ -0,0 +1 @@\n+x\n",
+		},
+		{
+			name: "deletion at the end", old: "a b c d", new: "a b c", context: 1,
+			want: "--- old\n+++ new\n@@ -3,2 +3 @@\n c\n-d\n",
+		},
+		{name: "added file", old: "", new: "a b c", context: 3, want: "--- old\n+++ new\n@@ -0,0 +1,3 @@\n+a\n+b\n+c\n"},
+		{name: "removed file", old: "a b", new: "", context: 3, want: "--- old\n+++ new\n@@ -1,2 +0,0 @@\n-a\n-b\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("old", "new", lines(tt.old), lines(tt.new), tt.context); got != tt.want {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
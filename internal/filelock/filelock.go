@@ -0,0 +1,24 @@
+// Package filelock provides advisory locks on files, used to serialize writers across processes.
+package filelock
+
+import "os"
+
+// Lock takes an exclusive advisory lock on the file at path, creating it if needed, and blocks until the lock is
+// held. The returned function releases the lock.
+func Lock(path string) (func() error, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lock(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return func() error {
+		if err := unlock(file); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	}, nil
+}
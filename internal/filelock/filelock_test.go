@@ -0,0 +1,62 @@
+package filelock
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockContended(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".lock")
+	release, err := Lock(path)
+	if err != nil {
+		t.Fatalf("Lock() unexpected error: %v", err)
+	}
+
+	acquired := make(chan func() error, 1)
+	errs := make(chan error, 1)
+	go func() {
+		secondRelease, err := Lock(path)
+		if err != nil {
+			errs <- err
+			return
+		}
+		acquired <- secondRelease
+	}()
+
+	// the second locker has to give up waiting while the first one holds the lock
+	select {
+	case <-acquired:
+		t.Fatal("second Lock() acquired the lock while the first one held it")
+	case err := <-errs:
+		t.Fatalf("second Lock() unexpected error: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if err := release(); err != nil {
+		t.Fatalf("release unexpected error: %v", err)
+	}
+	select {
+	case secondRelease := <-acquired:
+		if err := secondRelease(); err != nil {
+			t.Fatalf("second release unexpected error: %v", err)
+		}
+	case err := <-errs:
+		t.Fatalf("second Lock() unexpected error: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("second Lock() did not acquire the lock once it was released")
+	}
+}
+
+func TestLockReleased(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".lock")
+	for i := 0; i < 3; i++ {
+		release, err := Lock(path)
+		if err != nil {
+			t.Fatalf("Lock() %d unexpected error: %v", i, err)
+		}
+		if err := release(); err != nil {
+			t.Fatalf("release %d unexpected error: %v", i, err)
+		}
+	}
+}
@@ -1,151 +1,653 @@
 package helm
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/blang/semver/v4"
 	"github.com/enescakir/emoji"
+	"github.com/rmweir/rancher-upgrader/internal/clock"
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
+	"github.com/rmweir/rancher-upgrader/internal/proxy"
 	"github.com/sirupsen/logrus"
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
 	cli2 "helm.sh/helm/v3/pkg/cli"
-	"helm.sh/helm/v3/pkg/downloader"
 	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/postrender"
 	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
 	"helm.sh/helm/v3/pkg/repo"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
+// Options configures how the Client connects to the cluster.
+type Options struct {
+	KubeconfigPath string
+	// Namespace scopes all release lookups and upgrades to a single namespace. When empty, releases are listed
+	// across all namespaces, which requires cluster-wide list permissions.
+	Namespace string
+	// RancherNamespaceOnly looks for releases in the cattle-system namespace only when Namespace is empty, instead of
+	// falling back to all namespaces.
+	RancherNamespaceOnly bool
+	// ScanAllNamespaces always lists all namespaces for rancher releases when Namespace is empty, instead of only
+	// when none is found in cattle-system, so duplicate releases in other namespaces are found too.
+	ScanAllNamespaces bool
+	// OCIRepo is an oci:// reference to the rancher chart, e.g. oci://registry.example.com/rancher/rancher. When
+	// set, chart versions come from the registry's tags instead of the rancher-stable repo index.
+	OCIRepo string
+	// RepoTimeout bounds updating and loading the rancher-stable repo index. Zero means no timeout.
+	RepoTimeout time.Duration
+	// SkipRepoUpdate uses the cached rancher-stable repo index as is.
+	SkipRepoUpdate bool
+	// StayOnMinor only offers patches of the installed minor version, never the next minor version. It takes
+	// precedence over UpgradePolicy.
+	StayOnMinor bool
+	// UpgradePolicy picks between the newer versions that can be upgraded to. Empty means PolicyPatchFirst.
+	UpgradePolicy UpgradePolicy
+	// HelmDriver is the storage backend holding the helm release data, one of secret, configmap, memory or sql.
+	// Empty means secret.
+	HelmDriver string
+	// Verify requires every downloaded chart to come with a provenance file signed by a key of the GPG keyring at
+	// Keyring.
+	Verify  bool
+	Keyring string
+	// RepoUsername and RepoPassword authenticate to the rancher-stable repo with basic auth when the repositories file
+	// holds no credentials for it.
+	RepoUsername string
+	RepoPassword string
+	// MaxIndexAge is how old the rancher-stable repo index may be before a warning is shown. Zero disables the check.
+	MaxIndexAge time.Duration
+	// Clock tells the index age and waits between upgrade retries. Nil means the system clock.
+	Clock clock.Clock
+	// Proxy routes the repo and registry requests through a proxy instead of the one in the environment.
+	Proxy proxy.Config
+}
+
+var errTimeout = errors.New("timed out")
+
+// runWithTimeout runs f and gives up waiting on it once timeout has passed. f keeps running in the background after a
+// timeout, so it must be safe to abandon.
+func runWithTimeout(timeout time.Duration, f func() error) error {
+	if timeout <= 0 {
+		return f()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- f()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return errTimeout
+	}
+}
+
+// UpgradeOptions configures a single upgrade run.
+type UpgradeOptions struct {
+	// DryRun renders the upgraded release without applying it to the cluster.
+	DryRun bool
+	// PostRenderer is the path to an executable that receives the rendered manifests on stdin and writes the
+	// manifests to apply on stdout, same as helm's --post-renderer.
+	PostRenderer string
+	// Description and Labels are recorded on the release revision created by the upgrade.
+	Description string
+	Labels      map[string]string
+	// Atomic waits for the upgraded resources to be ready and rolls the release back if they are not.
+	Atomic bool
+	// Retries is how many more times an upgrade failing with a transient error is attempted. It requires Atomic, so
+	// every retry starts from the rolled back release instead of a partially applied one.
+	Retries int
+	// RetryBackoff is the wait before the first retry, doubled before each following one.
+	RetryBackoff time.Duration
+}
+
+// defaultAtomicTimeout is how long an atomic upgrade waits for resources to be ready, same as helm's default.
+const defaultAtomicTimeout = 5 * time.Minute
+
 type Client struct {
 	actionConfig *action.Configuration
-	index        *repo.IndexFile
+	settings     *cli2.EnvSettings
+	namespace    string
+	versions     VersionSource
+	stayOnMinor  bool
+	policy       UpgradePolicy
+	helmDriver   string
+	clock        clock.Clock
+	// scanAllNamespaces lists all namespaces for rancher releases even when one is found in the RancherNamespace
+	scanAllNamespaces bool
 }
 
-func NewClient(kubeconfigPath string) (Client, error) {
-	actionConfig := new(action.Configuration)
+func NewClient(opts Options) (Client, error) {
+	if err := validateUpgradePolicy(opts.UpgradePolicy); err != nil {
+		return Client{}, err
+	}
+	client, err := NewReleaseClient(opts)
+	if err != nil {
+		return Client{}, err
+	}
+
+	versions, err := newVersionSource(opts, client.settings)
+	if err != nil {
+		return Client{}, err
+	}
+	client.versions = versions
+	client.stayOnMinor = opts.StayOnMinor
+	client.policy = opts.UpgradePolicy
+	return client, nil
+}
 
+// NewChartClient returns a Client that can only look up and load rancher charts. It never talks to a cluster, so the
+// release lookups and upgrades must not be used.
+func NewChartClient(opts Options) (Client, error) {
+	if err := validateUpgradePolicy(opts.UpgradePolicy); err != nil {
+		return Client{}, err
+	}
 	settings := cli2.New()
-	settings.KubeConfig = kubeconfigPath
+	versions, err := newVersionSource(opts, settings)
+	if err != nil {
+		return Client{}, err
+	}
+	return Client{
+		settings:    settings,
+		versions:    versions,
+		stayOnMinor: opts.StayOnMinor,
+		policy:      opts.UpgradePolicy,
+		clock:       clock.OrReal(opts.Clock),
+	}, nil
+}
 
-	if err := actionConfig.Init(settings.RESTClientGetter(), "", os.Getenv("HELM_DRIVER"), logrus.Debugf); err != nil {
-		os.Exit(1)
+// newVersionSource returns the OCI registry source when opts.OCIRepo is set and the rancher-stable repo otherwise,
+// updating the repo index first unless opts.SkipRepoUpdate is set.
+func newVersionSource(opts Options, settings *cli2.EnvSettings) (VersionSource, error) {
+	var keyring string
+	if opts.Verify {
+		if opts.Keyring == "" {
+			return nil, fmt.Errorf("verifying charts requires a keyring, pass --keyring")
+		}
+		keyring = opts.Keyring
+	}
+	if opts.RepoPassword != "" && opts.RepoUsername == "" {
+		return nil, fmt.Errorf("a repo password requires a username, pass --repo-username")
+	}
+	if opts.OCIRepo != "" {
+		transport, err := repoTransport(opts.Proxy, nil)
+		if err != nil {
+			return nil, err
+		}
+		return newOCIVersionSource(opts.OCIRepo, keyring, settings, transport)
 	}
 
 	rancherStableRepo, err := verifyRancherStableRepoExists(settings.RepositoryConfig)
 	if err != nil {
-		return Client{}, err
+		return nil, err
 	}
 
-	if err := updateRepositories(settings.RepositoryCache, settings.RepositoryConfig); err != nil {
-		return Client{}, err
+	rancherStableRepo = withRepoCredentials(rancherStableRepo, opts.RepoUsername, opts.RepoPassword)
+	transport, err := repoTransport(opts.Proxy, rancherStableRepo)
+	if err != nil {
+		return nil, err
 	}
 
-	index, err := repo.LoadIndexFile(filepath.Join(settings.RepositoryCache, filepath.Join(helmpath.CacheIndexFile(rancherStableRepo.Name))))
+	var index *repo.IndexFile
+	err = runWithTimeout(opts.RepoTimeout, func() error {
+		if opts.SkipRepoUpdate {
+			fmt.Println(i18n.T(i18n.SkippingRepoUpdate))
+		} else if err := updateRancherStableRepo(settings.RepositoryCache, rancherStableRepo, transport, opts.RepoTimeout); err != nil {
+			return err
+		}
+
+		var err error
+		index, err = repo.LoadIndexFile(filepath.Join(settings.RepositoryCache, filepath.Join(helmpath.CacheIndexFile(rancherStableRepo.Name))))
+		return err
+	})
+	if errors.Is(err, errTimeout) {
+		return nil, fmt.Errorf("loading the rancher-stable repo index did not finish within %s, check that [%s] is reachable "+
+			"or re-run with --skip-repo-update to use the cached index", opts.RepoTimeout, rancherStableRepo.URL)
+	}
 	if err != nil {
-		return Client{}, err
+		return nil, err
+	}
+
+	if entries, ok := index.Entries["rancher"]; !ok {
+		return nil, fmt.Errorf("the selected repo [%s] at [%s] does not contain a 'rancher' chart", rancherStableRepo.Name, rancherStableRepo.URL)
+	} else if len(entries) == 0 {
+		// a truncated download or a mirror that is still syncing, not a repo without rancher
+		hint := "re-run without --skip-repo-update to refresh it"
+		if !opts.SkipRepoUpdate {
+			hint = "the update may have been truncated, try again"
+		}
+		return nil, fmt.Errorf("the repo index of [%s] at [%s] contains a rancher chart with no versions, %s", rancherStableRepo.Name, rancherStableRepo.URL, hint)
+	}
+
+	checkIndexAge(index, opts.MaxIndexAge, clock.OrReal(opts.Clock).Now())
+
+	return indexVersionSource{
+		index:     index,
+		repoName:  rancherStableRepo.Name,
+		keyring:   keyring,
+		settings:  settings,
+		username:  rancherStableRepo.Username,
+		password:  rancherStableRepo.Password,
+		transport: transport,
+	}, nil
+}
+
+// checkIndexAge reports when the index was generated and warns when it is older than maxAge, which usually means a
+// repo update failed without an error and the newest versions are missing. The age is measured at now.
+func checkIndexAge(index *repo.IndexFile, maxAge time.Duration, now time.Time) {
+	if index.Generated.IsZero() {
+		fmt.Println(i18n.T(i18n.IndexNotDated))
+		return
+	}
+	fmt.Println(i18n.T(i18n.UsingIndexGeneratedAt, index.Generated.Local().Format(time.RFC1123)))
+
+	age := now.Sub(index.Generated)
+	if maxAge > 0 && age > maxAge {
+		fmt.Println(i18n.T(i18n.IndexTooOld, emoji.Warning, age.Round(time.Hour), maxAge))
+	}
+}
+
+// NewReleaseClient returns a Client that can only inspect and upgrade releases. It skips the rancher-stable repo
+// setup, so the chart version lookups must not be used.
+func NewReleaseClient(opts Options) (Client, error) {
+	if opts.KubeconfigPath == "" {
+		return Client{}, fmt.Errorf("a kubeconfig is required, pass --kubeconfig or --kubeconfig-data")
+	}
+
+	actionConfig := new(action.Configuration)
+
+	namespace := opts.Namespace
+	if namespace == "" && opts.RancherNamespaceOnly {
+		namespace = RancherNamespace
+	}
+
+	settings := cli2.New()
+	settings.KubeConfig = opts.KubeconfigPath
+	if namespace != "" {
+		settings.SetNamespace(namespace)
+	}
+
+	switch opts.HelmDriver {
+	case "", "secret", "secrets", "configmap", "configmaps", "memory", "sql":
+	default:
+		return Client{}, fmt.Errorf("unsupported helm driver [%s], must be one of: secret, configmap, memory, sql", opts.HelmDriver)
+	}
+
+	if err := actionConfig.Init(settings.RESTClientGetter(), namespace, opts.HelmDriver, logrus.Debugf); err != nil {
+		return Client{}, fmt.Errorf("failed to initialize the helm client for kubeconfig [%s]: %w", opts.KubeconfigPath, err)
 	}
 
 	return Client{
 		actionConfig: actionConfig,
-		index:        index,
+		settings:     settings,
+		namespace:    namespace,
+		helmDriver:   opts.HelmDriver,
+		clock:        clock.OrReal(opts.Clock),
+		// only a client that is not scoped to a namespace scans all of them
+		scanAllNamespaces: opts.ScanAllNamespaces && namespace == "",
 	}, nil
 }
 
 func (c Client) ListReleases() ([]*release.Release, error) {
 	helmActionConfig := c.actionConfig
-	releases, err := action.NewList(helmActionConfig).Run()
+	listAction := action.NewList(helmActionConfig)
+	listAction.AllNamespaces = c.namespace == ""
+	releases, err := listAction.Run()
 	if err != nil {
-		return nil, err
+		if apierrors.IsForbidden(err) && c.namespace == "" {
+			return nil, fmt.Errorf("%w, or re-run with --namespace set to the namespace rancher is installed in. Listing "+
+				"releases across all namespaces needs a ClusterRole like:\n\n%s",
+				permissionError(err, "list helm releases", ""), listReleasesClusterRole(c.helmDriver))
+		}
+		return nil, permissionError(err, "list helm releases", c.namespace)
 	}
 	return releases, err
 }
 
-func (c Client) FindRancherRelease() (*release.Release, error) {
-	releases, err := c.ListReleases()
+// RancherNamespace is where rancher is installed almost every time, so it is searched before all namespaces are.
+const RancherNamespace = "cattle-system"
+
+// FindRancherReleases returns every release installed from a chart named rancher. Without a namespace the
+// RancherNamespace is searched first, and only when no rancher release is found there are all namespaces listed,
+// which is slow in large clusters. With ScanAllNamespaces all namespaces are always listed, to also find duplicate
+// releases outside of the RancherNamespace.
+func (c Client) FindRancherReleases() ([]*release.Release, error) {
+	return findRancherReleases(c.listReleasesIn, c.namespace, c.scanAllNamespaces)
+}
+
+// findRancherReleases looks for rancher releases with list, which lists the releases of a namespace or, given an empty
+// namespace, of all namespaces.
+func findRancherReleases(list func(namespace string) ([]*release.Release, error), namespace string, scanAll bool) ([]*release.Release, error) {
+	if namespace == "" && !scanAll {
+		releases, err := list(RancherNamespace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look for the rancher release in [%s]: %w", RancherNamespace, err)
+		}
+		if rancherReleases := filterRancherReleases(releases); len(rancherReleases) != 0 {
+			return rancherReleases, nil
+		}
+	}
+
+	releases, err := list(namespace)
 	if err != nil {
 		return nil, err
 	}
+	rancherReleases := filterRancherReleases(releases)
+	if len(rancherReleases) == 0 {
+		return nil, fmt.Errorf("rancher release could not be found")
+	}
+	return rancherReleases, nil
+}
 
+// listReleasesIn lists the releases of namespace, or of the client's own scope when namespace is its namespace.
+func (c Client) listReleasesIn(namespace string) ([]*release.Release, error) {
+	if namespace == c.namespace {
+		return c.ListReleases()
+	}
+	actionConfig, err := c.actionConfigFor(namespace)
+	if err != nil {
+		return nil, err
+	}
+	releases, err := action.NewList(actionConfig).Run()
+	if err != nil {
+		return nil, permissionError(err, "list helm releases", namespace)
+	}
+	return releases, nil
+}
+
+// actionConfigFor returns a helm configuration whose release storage and kube client are both scoped to namespace.
+// The client's own configuration is unscoped when no namespace was given, which is fine for listing releases but
+// writes release records and un-namespaced resources to the wrong place, so everything acting on a single release
+// uses the configuration of its namespace.
+func (c Client) actionConfigFor(namespace string) (*action.Configuration, error) {
+	if namespace == c.namespace {
+		return c.actionConfig, nil
+	}
+	settings := cli2.New()
+	settings.KubeConfig = c.settings.KubeConfig
+	settings.SetNamespace(namespace)
+	actionConfig := new(action.Configuration)
+	if err := actionConfig.Init(settings.RESTClientGetter(), namespace, c.helmDriver, logrus.Debugf); err != nil {
+		return nil, fmt.Errorf("failed to initialize the helm client for namespace [%s]: %w", namespace, err)
+	}
+	return actionConfig, nil
+}
+
+func filterRancherReleases(releases []*release.Release) []*release.Release {
+	var rancherReleases []*release.Release
 	for _, release := range releases {
 		if release.Chart.Metadata.Name == "rancher" {
-			fmt.Printf("Found rancher release [%s] in namespace [%s]\n", release.Name, release.Namespace)
-			fmt.Printf("Is %s:%s the rancher release you would like to upgrade?\n", release.Name, release.Namespace)
-			return release, nil
+			rancherReleases = append(rancherReleases, release)
 		}
 	}
-	return nil, fmt.Errorf("rancher release could not be found")
+	return rancherReleases
+}
+
+// History returns every stored revision of the named release of namespace, oldest first. Releases of the same name
+// in other namespaces are left out.
+func (c Client) History(namespace, releaseName string) ([]*release.Release, error) {
+	actionConfig, err := c.actionConfigFor(namespace)
+	if err != nil {
+		return nil, err
+	}
+	revisions, err := action.NewHistory(actionConfig).Run(releaseName)
+	if err != nil {
+		return nil, permissionError(err, fmt.Sprintf("read the history of release [%s]", releaseName), namespace)
+	}
+	releaseutil.SortByRevision(revisions)
+	return revisions, nil
+}
+
+const (
+	rancherStableRepoName = "rancher-stable"
+	rancherStableRepoURL  = "https://releases.rancher.com/server-charts/stable"
+)
+
+// MissingRepoError is returned when none of the configured helm repositories serves the rancher-stable charts.
+type MissingRepoError struct {
+	// RepositoryConfig is the helm repositories file that was searched.
+	RepositoryConfig string
+	// Repositories are the configured repositories, as name (url).
+	Repositories []string
+}
+
+func (e *MissingRepoError) Error() string {
+	configured := "none"
+	if len(e.Repositories) != 0 {
+		configured = strings.Join(e.Repositories, ", ")
+	}
+	return fmt.Sprintf("no repository matching \"releases.rancher.com/server-charts/stable\" found in [%s], configured repositories: %s",
+		e.RepositoryConfig, configured)
+}
+
+// Remediation is the command that adds the missing repository.
+func (e *MissingRepoError) Remediation() string {
+	return fmt.Sprintf("helm repo add %s %s", rancherStableRepoName, rancherStableRepoURL)
+}
+
+// RepositoryConfig returns the path of the helm repositories file and whether it exists.
+func RepositoryConfig() (string, bool, error) {
+	path := cli2.New().RepositoryConfig
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return path, false, nil
+		}
+		return path, false, err
+	}
+	return path, true, nil
+}
+
+// CreateRepositoryConfig writes an empty helm repositories file, the same one helm writes on its first repo add.
+func CreateRepositoryConfig() error {
+	path := cli2.New().RepositoryConfig
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return repo.NewFile().WriteFile(path, 0600)
+}
+
+// FindRancherStableRepo returns the name of the configured repository serving the rancher-stable charts, or a
+// *MissingRepoError when there is none.
+func FindRancherStableRepo() (string, error) {
+	entry, err := verifyRancherStableRepoExists(cli2.New().RepositoryConfig)
+	if err != nil {
+		return "", err
+	}
+	return entry.Name, nil
+}
+
+// AddRancherStableRepo adds the rancher-stable repository to the helm repositories file and downloads its index, the
+// same as the command in MissingRepoError.Remediation.
+func AddRancherStableRepo() error {
+	settings := cli2.New()
+	repoFile, err := repo.LoadFile(settings.RepositoryConfig)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if repoFile == nil || repoFile.Repositories == nil {
+		repoFile = repo.NewFile()
+	}
+	if repoFile.Has(rancherStableRepoName) {
+		return fmt.Errorf("a repository named [%s] already exists in [%s], add %s under another name", rancherStableRepoName, settings.RepositoryConfig, rancherStableRepoURL)
+	}
+
+	entry := &repo.Entry{Name: rancherStableRepoName, URL: rancherStableRepoURL}
+	chartRepo, err := repo.NewChartRepository(entry, httpGetters(nil))
+	if err != nil {
+		return err
+	}
+	chartRepo.CachePath = settings.RepositoryCache
+	if _, err := chartRepo.DownloadIndexFile(); err != nil {
+		return fmt.Errorf("failed to download the index of [%s]: %w", rancherStableRepoURL, err)
+	}
+
+	repoFile.Update(entry)
+	if err := os.MkdirAll(filepath.Dir(settings.RepositoryConfig), 0755); err != nil {
+		return err
+	}
+	return repoFile.WriteFile(settings.RepositoryConfig, 0600)
 }
 
 func verifyRancherStableRepoExists(repoConfigPath string) (*repo.Entry, error) {
-	fmt.Println("Verifying rancher-stable repo exists...")
+	fmt.Println(i18n.T(i18n.VerifyingRepo))
 	f, err := repo.LoadFile(repoConfigPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, &MissingRepoError{RepositoryConfig: repoConfigPath}
+	}
 	if err != nil {
 		return nil, err
 	}
+	configured := make([]string, 0, len(f.Repositories))
 	for _, repo := range f.Repositories {
-		isRancherStableRepo := strings.HasSuffix(strings.TrimSuffix(repo.URL, "/"), "releases.rancher.com/server-charts/stable")
-		if isRancherStableRepo {
-			fmt.Printf("%v Rancher-stable repo found!\n", emoji.ThumbsUp)
+		if isRancherStableRepo(repo.URL) {
+			fmt.Println(i18n.T(i18n.RepoFound, emoji.ThumbsUp))
 			return repo, nil
 		}
+		configured = append(configured, fmt.Sprintf("%s (%s)", repo.Name, repo.URL))
 	}
 
-	return nil, fmt.Errorf("no repository found matach \"releases.rancher.com/server-charts/stable\"")
+	return nil, &MissingRepoError{RepositoryConfig: repoConfigPath, Repositories: configured}
 }
 
-func updateRepositories(repoCachePath, repoConfigPath string) error {
-	manager := downloader.Manager{
-		RepositoryCache:  repoCachePath,
-		RepositoryConfig: repoConfigPath,
-		Out:              os.Stdout,
-		Getters: getter.Providers{getter.Provider{
-			Schemes: []string{"http", "https"},
-			New:     getter.NewHTTPGetter,
-		}},
+// withRepoCredentials returns a copy of entry authenticating with username and password, unless the repositories
+// file already holds credentials for it.
+func withRepoCredentials(entry *repo.Entry, username, password string) *repo.Entry {
+	if entry.Username != "" || username == "" {
+		return entry
 	}
+	withCredentials := *entry
+	withCredentials.Username = username
+	withCredentials.Password = password
+	return &withCredentials
+}
 
-	return manager.UpdateRepositories()
+// isRancherStableRepo reports whether the repo at url serves the rancher-stable charts.
+func isRancherStableRepo(url string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(url, "/"), "releases.rancher.com/server-charts/stable")
+}
+
+// updateRancherStableRepo refreshes the cached index of the rancher-stable repo, the repo versions are looked up in.
+// The download is aborted after timeout, so an unreachable mirror does not keep a request open once the caller gave up
+// on it. 0 waits forever.
+func updateRancherStableRepo(repoCachePath string, entry *repo.Entry, transport *http.Transport, timeout time.Duration) error {
+	fmt.Println(i18n.T(i18n.UpdatingRepoIndex, entry.Name))
+	var options []getter.Option
+	if timeout > 0 {
+		options = append(options, getter.WithTimeout(timeout))
+	}
+	chartRepo, err := repo.NewChartRepository(entry, httpGetters(transport, options...))
+	if err != nil {
+		return err
+	}
+	chartRepo.CachePath = repoCachePath
+	if _, err := chartRepo.DownloadIndexFile(); err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return errTimeout
+		}
+		if entry.Username != "" {
+			return fmt.Errorf("failed to update the %s repo index at [%s] as user [%s]: %w", entry.Name, entry.URL, entry.Username, err)
+		}
+		return fmt.Errorf("failed to update the %s repo index at [%s]: %w", entry.Name, entry.URL, err)
+	}
+	return nil
 }
 
 func (c Client) GetNextSupportedRancherChartVersion(currentVersion string) (string, error) {
+	availableVersions, err := c.versions.AvailableVersions()
+	if err != nil {
+		return "", err
+	}
+	return NextSupportedVersion(currentVersion, availableVersions, c.stayOnMinor, c.policy)
+}
+
+// NextSupportedVersion picks the upgrade target for currentVersion out of availableVersions. With PolicyPatchFirst it
+// follows rancher's supported upgrade path: the latest patch of the current minor, or once on it, the latest patch of
+// the next minor unless stayOnMinor is set. The other policies are described on their constants. currentVersion is
+// returned when there is nothing newer to upgrade to.
+func NextSupportedVersion(currentVersion string, availableVersions []semver.Version, stayOnMinor bool, policy UpgradePolicy) (string, error) {
 	currentChartVersion, err := semver.New(currentVersion)
 	if err != nil {
 		return "", err
 	}
 
-	c.index.SortEntries()
-	nextMinorUpgrade := ""
-	latestPatchOnCurrentMinorVersion := ""
-	for _, chartVersion := range c.index.Entries["rancher"] {
-		chartSemver, err := semver.New(chartVersion.Version)
-		if err != nil {
-			return "", err
+	// newest first, copied so the caller's slice is left untouched. Repo indexes and registry tags come in any order,
+	// so nothing below relies on the order they were listed in.
+	sortedVersions := append([]semver.Version(nil), availableVersions...)
+	sort.Sort(sort.Reverse(semver.Versions(sortedVersions)))
+
+	var newestOnMajor, nextMinorUpgrade, latestPatchOnCurrentMinorVersion *semver.Version
+	for i := range sortedVersions {
+		chartSemver := &sortedVersions[i]
+		if chartSemver.Major != currentChartVersion.Major {
+			continue
+		}
+		if newestOnMajor == nil {
+			newestOnMajor = chartSemver
 		}
-		if nextMinorUpgrade == "" && chartSemver.Minor-1 == currentChartVersion.Minor {
-			nextMinorUpgrade = chartVersion.Version
+		if nextMinorUpgrade == nil && chartSemver.Minor == currentChartVersion.Minor+1 {
+			nextMinorUpgrade = chartSemver
 			continue
 		}
 		if chartSemver.Minor != currentChartVersion.Minor {
 			continue
 		}
-		latestPatchOnCurrentMinorVersion = chartVersion.Version
+		latestPatchOnCurrentMinorVersion = chartSemver
 		break
 	}
 
-	if latestPatchOnCurrentMinorVersion == "" {
-		// should always be able to detect latest patch for current minor version
-		return "", fmt.Errorf("there was an issue detecting the next supported rancher chart version: could not"+
-			"detect latest patch for line [%d.%d.x]", currentChartVersion.Major, currentChartVersion.Minor)
+	if !stayOnMinor {
+		if policy == PolicyNewest && newestOnMajor != nil && newestOnMajor.Minor > currentChartVersion.Minor+1 {
+			fmt.Println(i18n.T(i18n.SkipsMinorVersions, emoji.Warning, currentVersion, newestOnMajor))
+			return newestOnMajor.String(), nil
+		}
+		if (policy == PolicyNewest || policy == PolicyMinorFirst) && nextMinorUpgrade != nil {
+			return nextMinorUpgrade.String(), nil
+		}
+	}
+
+	if latestPatchOnCurrentMinorVersion == nil {
+		// the whole line was pruned from the repo, which happens to old minor versions
+		if nextMinorUpgrade != nil && !stayOnMinor {
+			fmt.Println(i18n.T(i18n.MinorLinePruned, emoji.Warning, currentChartVersion.Major, currentChartVersion.Minor, nextMinorUpgrade))
+			return nextMinorUpgrade.String(), nil
+		}
+		oldestNewer := ""
+		for i := len(sortedVersions) - 1; i >= 0; i-- {
+			if sortedVersions[i].GT(*currentChartVersion) {
+				oldestNewer = sortedVersions[i].String()
+				break
+			}
+		}
+		if oldestNewer == "" {
+			return "", fmt.Errorf("no [%d.%d.x] or newer rancher chart versions are available in the repo",
+				currentChartVersion.Major, currentChartVersion.Minor)
+		}
+		return "", fmt.Errorf("no [%d.%d.x] rancher chart versions are available in the repo, they may have been pruned, "+
+			"and no supported upgrade path leads from [%s] to the oldest available newer version [%s]",
+			currentChartVersion.Major, currentChartVersion.Minor, currentVersion, oldestNewer)
 	}
 
-	if currentVersion != latestPatchOnCurrentMinorVersion {
-		return latestPatchOnCurrentMinorVersion, nil
+	if latestPatchOnCurrentMinorVersion.GT(*currentChartVersion) {
+		return latestPatchOnCurrentMinorVersion.String(), nil
 	}
 
-	if nextMinorUpgrade != "" {
-		return nextMinorUpgrade, nil
+	if nextMinorUpgrade != nil && !stayOnMinor {
+		return nextMinorUpgrade.String(), nil
 	}
 
 	// if the current version is equal to latest patch on that version's minor and there is no next minor upgrade,
@@ -153,17 +655,190 @@ func (c Client) GetNextSupportedRancherChartVersion(currentVersion string) (stri
 	return currentVersion, nil
 }
 
+// GetUpgradePath returns every version to upgrade through, in order, to get from currentVersion to the newest version
+// while following the supported upgrade path. It is empty when currentVersion is up-to-date.
+func (c Client) GetUpgradePath(currentVersion string) ([]string, error) {
+	availableVersions, err := c.versions.AvailableVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	var upgradePath []string
+	version := currentVersion
+	for {
+		nextVersion, err := NextSupportedVersion(version, availableVersions, c.stayOnMinor, c.policy)
+		if err != nil {
+			return nil, err
+		}
+		if nextVersion == version {
+			return upgradePath, nil
+		}
+		upgradePath = append(upgradePath, nextVersion)
+		version = nextVersion
+	}
+}
+
+// ListRancherChartVersions returns every available rancher chart version, oldest first. Sources without index
+// metadata, like OCI registries, only fill in the version.
+func (c Client) ListRancherChartVersions() ([]*repo.ChartVersion, error) {
+	availableVersions, err := c.versions.AvailableVersions()
+	if err != nil {
+		return nil, err
+	}
+	sortedVersions := append([]semver.Version(nil), availableVersions...)
+	sort.Sort(semver.Versions(sortedVersions))
+
+	chartVersions := make([]*repo.ChartVersion, 0, len(sortedVersions))
+	for _, version := range sortedVersions {
+		chartVersion, err := c.GetRancherChartForVersion(version.String())
+		if err != nil {
+			return nil, err
+		}
+		chartVersions = append(chartVersions, chartVersion)
+	}
+	return chartVersions, nil
+}
+
+// GetRancherChartForAppVersion returns the chart version shipping the rancher appVersion, e.g. v2.7.9. Sources
+// without index metadata, like OCI registries, are assumed to tag charts with the rancher version.
+func (c Client) GetRancherChartForAppVersion(appVersion string) (*repo.ChartVersion, error) {
+	if lookup, ok := c.versions.(chartVersionLookup); ok {
+		return lookup.ChartVersionForAppVersion(appVersion)
+	}
+	chartVersion, err := c.GetRancherChartForVersion(strings.TrimPrefix(appVersion, "v"))
+	if err != nil {
+		return nil, fmt.Errorf("no rancher chart with appVersion [%s] found: %w", appVersion, err)
+	}
+	return chartVersion, nil
+}
+
 func (c Client) GetRancherChartForVersion(version string) (*repo.ChartVersion, error) {
-	return c.index.Get("rancher", version)
+	if lookup, ok := c.versions.(chartVersionLookup); ok {
+		return lookup.ChartVersion(version)
+	}
+
+	availableVersions, err := c.versions.AvailableVersions()
+	if err != nil {
+		return nil, err
+	}
+	for _, availableVersion := range availableVersions {
+		if availableVersion.String() == version {
+			// the source carries no index metadata, only the version is known without loading the chart
+			return &repo.ChartVersion{Metadata: &chart.Metadata{Name: "rancher", Version: version}}, nil
+		}
+	}
+	return nil, fmt.Errorf("rancher chart version [%s] not found", version)
+}
+
+func (c Client) LoadRancherChart(version string) (*chart.Chart, error) {
+	return c.versions.Chart(version)
 }
 
-func (c Client) Upgrade(release *release.Release, overrideValues map[string]interface{}) (*release.Release, error) {
-	upgradeAction := action.NewUpgrade(c.actionConfig)
-	upgradeAction.DryRun = true
+// LoadLocalChart loads a chart directory or archive from disk. Dependencies are used as they are vendored in the
+// chart, nothing is downloaded.
+func LoadLocalChart(path string) (*chart.Chart, error) {
+	localChart, err := loader.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart [%s]: %w", path, err)
+	}
+	if err := action.CheckDependencies(localChart, localChart.Metadata.Dependencies); err != nil {
+		return nil, fmt.Errorf("chart [%s] is missing vendored dependencies: %w", path, err)
+	}
+	return localChart, nil
+}
 
-	newRelease, err := upgradeAction.Run(release.Name, release.Chart, overrideValues)
+func (c Client) Upgrade(release *release.Release, targetChart *chart.Chart, overrideValues map[string]interface{}, opts UpgradeOptions) (*release.Release, error) {
+	actionConfig, err := c.actionConfigFor(release.Namespace)
 	if err != nil {
 		return nil, err
 	}
-	return newRelease, nil
+	upgradeAction := action.NewUpgrade(actionConfig)
+	upgradeAction.Namespace = release.Namespace
+	upgradeAction.DryRun = opts.DryRun
+	upgradeAction.Description = opts.Description
+	upgradeAction.Labels = opts.Labels
+	if opts.PostRenderer != "" {
+		postRenderer, err := postrender.NewExec(opts.PostRenderer)
+		if err != nil {
+			return nil, err
+		}
+		upgradeAction.PostRenderer = postRenderer
+	}
+
+	if opts.Atomic {
+		upgradeAction.Atomic = true
+		upgradeAction.Wait = true
+		upgradeAction.Timeout = defaultAtomicTimeout
+	}
+	if opts.Retries > 0 && !opts.Atomic {
+		return nil, fmt.Errorf("retrying upgrades requires --atomic, a failed upgrade must be rolled back before it is retried")
+	}
+
+	backoff := opts.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		newRelease, err := upgradeAction.Run(release.Name, targetChart, overrideValues)
+		if err == nil {
+			return newRelease, nil
+		}
+		if attempt > opts.Retries || !isRetryable(err) {
+			return nil, permissionError(err, fmt.Sprintf("upgrade release [%s]", release.Name), release.Namespace)
+		}
+		fmt.Println(i18n.T(i18n.RetryingUpgrade, emoji.Warning, attempt, opts.Retries+1, err, backoff))
+		c.clock.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// isRetryable reports whether err is a transient API server or webhook failure that an identical upgrade can get
+// past, as opposed to an error in the chart or values.
+func isRetryable(err error) bool {
+	if apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) || apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) || apierrors.IsInternalError(err) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	message := err.Error()
+	for _, transient := range []string{"failed calling webhook", "connection refused", "connection reset by peer", "i/o timeout", "TLS handshake timeout"} {
+		if strings.Contains(message, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rollback rolls the release of namespace back to the given revision, creating a new revision with its chart and
+// values.
+func (c Client) Rollback(namespace, releaseName string, revision int) error {
+	actionConfig, err := c.actionConfigFor(namespace)
+	if err != nil {
+		return err
+	}
+	rollbackAction := action.NewRollback(actionConfig)
+	rollbackAction.Version = revision
+	if err := rollbackAction.Run(releaseName); err != nil {
+		return permissionError(err, fmt.Sprintf("roll back release [%s]", releaseName), namespace)
+	}
+	return nil
+}
+
+// Render renders targetChart with overrideValues the way helm template does, without a cluster, so rendering and
+// values schema errors show up before an upgrade is attempted. It returns the rendered manifest.
+func (c Client) Render(release *release.Release, targetChart *chart.Chart, overrideValues map[string]interface{}) (string, error) {
+	// client only installs replace the configuration's kube client and storage, so they get a configuration of their own
+	installAction := action.NewInstall(&action.Configuration{Log: logrus.Debugf})
+	installAction.ClientOnly = true
+	installAction.DryRun = true
+	installAction.Replace = true
+	installAction.ReleaseName = release.Name
+	installAction.Namespace = release.Namespace
+	installAction.IsUpgrade = true
+
+	rendered, err := installAction.Run(targetChart, overrideValues)
+	if err != nil {
+		return "", err
+	}
+	return rendered.Manifest, nil
 }
@@ -0,0 +1,456 @@
+package helm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"helm.sh/helm/v3/pkg/chart"
+	cli2 "helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// testRepo writes a repositories file with a rancher-stable repo and its cached index holding entries, and returns
+// settings pointing at them.
+func testRepo(t *testing.T, entries map[string]repo.ChartVersions) *cli2.EnvSettings {
+	t.Helper()
+	dir := t.TempDir()
+	settings := cli2.New()
+	settings.RepositoryConfig = filepath.Join(dir, "repositories.yaml")
+	settings.RepositoryCache = filepath.Join(dir, "cache")
+
+	repoFile := repo.NewFile()
+	repoFile.Update(&repo.Entry{Name: rancherStableRepoName, URL: rancherStableRepoURL})
+	if err := repoFile.WriteFile(settings.RepositoryConfig, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(settings.RepositoryCache, 0755); err != nil {
+		t.Fatal(err)
+	}
+	index := repo.NewIndexFile()
+	index.Entries = entries
+	if err := index.WriteFile(filepath.Join(settings.RepositoryCache, helmpath.CacheIndexFile(rancherStableRepoName)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return settings
+}
+
+func rancherChartVersions(versions ...string) repo.ChartVersions {
+	chartVersions := make(repo.ChartVersions, 0, len(versions))
+	for _, version := range versions {
+		chartVersions = append(chartVersions, &repo.ChartVersion{
+			Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "rancher", Version: version, AppVersion: "v" + version},
+			URLs:     []string{"https://releases.rancher.com/server-charts/stable/rancher-" + version + ".tgz"},
+		})
+	}
+	return chartVersions
+}
+
+func TestNewVersionSource(t *testing.T) {
+	tests := []struct {
+		name           string
+		entries        map[string]repo.ChartVersions
+		skipRepoUpdate bool
+		// wantNext is the version offered after 2.6.3
+		wantNext string
+		wantErr  string
+	}{
+		{
+			name:           "unsorted index",
+			entries:        map[string]repo.ChartVersions{"rancher": rancherChartVersions("2.7.1", "2.6.9", "2.8.2", "2.6.13", "2.7.5")},
+			skipRepoUpdate: true,
+			wantNext:       "2.6.13",
+		},
+		{
+			name:           "empty rancher entry",
+			entries:        map[string]repo.ChartVersions{"rancher": {}},
+			skipRepoUpdate: true,
+			wantErr: "the repo index of [rancher-stable] at [https://releases.rancher.com/server-charts/stable] contains a " +
+				"rancher chart with no versions, re-run without --skip-repo-update to refresh it",
+		},
+		{
+			name:           "no rancher entry",
+			entries:        map[string]repo.ChartVersions{"rancher-webhook": rancherChartVersions("2.0.5")},
+			skipRepoUpdate: true,
+			wantErr:        "the selected repo [rancher-stable] at [https://releases.rancher.com/server-charts/stable] does not contain a 'rancher' chart",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := testRepo(t, tt.entries)
+			source, err := newVersionSource(Options{SkipRepoUpdate: tt.skipRepoUpdate}, settings)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("newVersionSource() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newVersionSource() unexpected error: %v", err)
+			}
+			client := Client{versions: source, policy: PolicyPatchFirst}
+			got, err := client.GetNextSupportedRancherChartVersion("2.6.3")
+			if err != nil {
+				t.Fatalf("GetNextSupportedRancherChartVersion() unexpected error: %v", err)
+			}
+			if got != tt.wantNext {
+				t.Errorf("GetNextSupportedRancherChartVersion() = %s, want %s", got, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestNewVersionSourceRepoTimeout(t *testing.T) {
+	release := make(chan struct{})
+	unresponsive := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer unresponsive.Close()
+	defer close(release)
+
+	settings := testRepo(t, map[string]repo.ChartVersions{"rancher": rancherChartVersions("2.7.5")})
+	// a path ending like the rancher-stable URL is taken for a mirror of it
+	mirrorURL := unresponsive.URL + "/releases.rancher.com/server-charts/stable"
+	repoFile := repo.NewFile()
+	repoFile.Update(&repo.Entry{Name: rancherStableRepoName, URL: mirrorURL})
+	if err := repoFile.WriteFile(settings.RepositoryConfig, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	timeout := 200 * time.Millisecond
+	start := time.Now()
+	_, err := newVersionSource(Options{RepoTimeout: timeout}, settings)
+	elapsed := time.Since(start)
+
+	wantErr := fmt.Sprintf("loading the rancher-stable repo index did not finish within %s, check that [%s] is reachable "+
+		"or re-run with --skip-repo-update to use the cached index", timeout, mirrorURL)
+	if err == nil || err.Error() != wantErr {
+		t.Fatalf("newVersionSource() error = %v, want %q", err, wantErr)
+	}
+	// generous slack for slow CI machines, a hang would block until the test times out
+	if elapsed > timeout+2*time.Second {
+		t.Errorf("newVersionSource() returned after %s, want about %s", elapsed, timeout)
+	}
+
+	// the download itself gives up too, rather than being left hanging in the background
+	start = time.Now()
+	err = updateRancherStableRepo(t.TempDir(), &repo.Entry{Name: rancherStableRepoName, URL: mirrorURL}, nil, timeout)
+	if !errors.Is(err, errTimeout) {
+		t.Errorf("updateRancherStableRepo() error = %v, want %v", err, errTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > timeout+2*time.Second {
+		t.Errorf("updateRancherStableRepo() returned after %s, want about %s", elapsed, timeout)
+	}
+}
+
+func TestNextSupportedVersion(t *testing.T) {
+	// listed out of order, 2.6.9 sorts after 2.6.13 as text
+	unsorted := []string{"2.7.1", "2.6.3", "2.9.1", "2.6.9", "2.7.5", "2.6.13", "2.8.0"}
+	// the 2.6 line was pruned
+	pruned := []string{"2.7.5", "2.8.0", "2.7.2"}
+	// the 2.6 and 2.7 lines were pruned
+	prunedWithoutNextMinor := []string{"2.8.3", "2.8.0"}
+
+	tests := []struct {
+		name        string
+		current     string
+		available   []string
+		stayOnMinor bool
+		policy      UpgradePolicy
+		want        string
+		wantErr     string
+	}{
+		{name: "unsorted patch-first", current: "2.6.3", available: unsorted, policy: PolicyPatchFirst, want: "2.6.13"},
+		{name: "unsorted default policy", current: "2.6.3", available: unsorted, want: "2.6.13"},
+		{name: "unsorted minor-first", current: "2.6.3", available: unsorted, policy: PolicyMinorFirst, want: "2.7.5"},
+		{name: "unsorted newest", current: "2.6.3", available: unsorted, policy: PolicyNewest, want: "2.9.1"},
+		{name: "unsorted on latest patch", current: "2.6.13", available: unsorted, policy: PolicyPatchFirst, want: "2.7.5"},
+		{name: "unsorted stay on minor", current: "2.6.13", available: unsorted, stayOnMinor: true, policy: PolicyMinorFirst, want: "2.6.13"},
+		{name: "unsorted up-to-date", current: "2.9.1", available: unsorted, policy: PolicyPatchFirst, want: "2.9.1"},
+
+		{name: "pruned patch-first", current: "2.6.5", available: pruned, policy: PolicyPatchFirst, want: "2.7.5"},
+		{name: "pruned minor-first", current: "2.6.5", available: pruned, policy: PolicyMinorFirst, want: "2.7.5"},
+		{name: "pruned newest", current: "2.6.5", available: pruned, policy: PolicyNewest, want: "2.8.0"},
+		{
+			name: "pruned stay on minor", current: "2.6.5", available: pruned, stayOnMinor: true, policy: PolicyPatchFirst,
+			wantErr: "no [2.6.x] rancher chart versions are available in the repo, they may have been pruned, and no " +
+				"supported upgrade path leads from [2.6.5] to the oldest available newer version [2.7.2]",
+		},
+		{
+			name: "pruned without next minor patch-first", current: "2.6.5", available: prunedWithoutNextMinor, policy: PolicyPatchFirst,
+			wantErr: "no [2.6.x] rancher chart versions are available in the repo, they may have been pruned, and no " +
+				"supported upgrade path leads from [2.6.5] to the oldest available newer version [2.8.0]",
+		},
+		{
+			name: "pruned without next minor minor-first", current: "2.6.5", available: prunedWithoutNextMinor, policy: PolicyMinorFirst,
+			wantErr: "no [2.6.x] rancher chart versions are available in the repo, they may have been pruned, and no " +
+				"supported upgrade path leads from [2.6.5] to the oldest available newer version [2.8.0]",
+		},
+		{name: "pruned without next minor newest", current: "2.6.5", available: prunedWithoutNextMinor, policy: PolicyNewest, want: "2.8.3"},
+		{
+			name: "nothing newer", current: "2.6.5", available: []string{"2.5.9"}, policy: PolicyPatchFirst,
+			wantErr: "no [2.6.x] or newer rancher chart versions are available in the repo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			available := make([]semver.Version, 0, len(tt.available))
+			for _, version := range tt.available {
+				available = append(available, semver.MustParse(version))
+			}
+			got, err := NextSupportedVersion(tt.current, available, tt.stayOnMinor, tt.policy)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("NextSupportedVersion() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NextSupportedVersion() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("NextSupportedVersion() = %s, want %s", got, tt.want)
+			}
+			if strings.Join(tt.available, ",") != strings.Join(versionStrings(available), ",") {
+				t.Errorf("NextSupportedVersion() reordered the available versions to %v", available)
+			}
+		})
+	}
+}
+
+func versionStrings(versions []semver.Version) []string {
+	strs := make([]string, 0, len(versions))
+	for _, version := range versions {
+		strs = append(strs, version.String())
+	}
+	return strs
+}
+
+func TestVerifyRancherStableRepoExists(t *testing.T) {
+	tests := []struct {
+		name         string
+		repositories []*repo.Entry
+		// noFile leaves the repositories file out, as before the first helm repo add
+		noFile           bool
+		wantRepo         string
+		wantRepositories []string
+		wantErr          string
+	}{
+		{
+			name:         "found",
+			repositories: []*repo.Entry{{Name: "bitnami", URL: "https://charts.bitnami.com/bitnami"}, {Name: "stable", URL: "https://releases.rancher.com/server-charts/stable/"}},
+			wantRepo:     "stable",
+		},
+		{
+			name:    "no repositories file",
+			noFile:  true,
+			wantErr: `no repository matching "releases.rancher.com/server-charts/stable" found in [%s], configured repositories: none`,
+		},
+		{
+			name:    "no repositories",
+			wantErr: `no repository matching "releases.rancher.com/server-charts/stable" found in [%s], configured repositories: none`,
+		},
+		{
+			name: "other repositories",
+			repositories: []*repo.Entry{
+				{Name: "rancher-latest", URL: "https://releases.rancher.com/server-charts/latest"},
+				{Name: "bitnami", URL: "https://charts.bitnami.com/bitnami"},
+			},
+			wantRepositories: []string{"rancher-latest (https://releases.rancher.com/server-charts/latest)", "bitnami (https://charts.bitnami.com/bitnami)"},
+			wantErr: `no repository matching "releases.rancher.com/server-charts/stable" found in [%s], configured repositories: ` +
+				`rancher-latest (https://releases.rancher.com/server-charts/latest), bitnami (https://charts.bitnami.com/bitnami)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "repositories.yaml")
+			if !tt.noFile {
+				repoFile := repo.NewFile()
+				for _, entry := range tt.repositories {
+					repoFile.Update(entry)
+				}
+				if err := repoFile.WriteFile(path, 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			entry, err := verifyRancherStableRepoExists(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyRancherStableRepoExists() unexpected error: %v", err)
+				}
+				if entry.Name != tt.wantRepo {
+					t.Errorf("verifyRancherStableRepoExists() = %s, want %s", entry.Name, tt.wantRepo)
+				}
+				return
+			}
+
+			var missingRepo *MissingRepoError
+			if !errors.As(err, &missingRepo) {
+				t.Fatalf("verifyRancherStableRepoExists() error = %v, want a *MissingRepoError", err)
+			}
+			if missingRepo.RepositoryConfig != path {
+				t.Errorf("RepositoryConfig = %s, want %s", missingRepo.RepositoryConfig, path)
+			}
+			if strings.Join(missingRepo.Repositories, "|") != strings.Join(tt.wantRepositories, "|") {
+				t.Errorf("Repositories = %v, want %v", missingRepo.Repositories, tt.wantRepositories)
+			}
+			if want := fmt.Sprintf(tt.wantErr, path); err.Error() != want {
+				t.Errorf("Error() = %q, want %q", err.Error(), want)
+			}
+			if want := "helm repo add rancher-stable https://releases.rancher.com/server-charts/stable"; missingRepo.Remediation() != want {
+				t.Errorf("Remediation() = %q, want %q", missingRepo.Remediation(), want)
+			}
+		})
+	}
+}
+
+func TestChartLookupsWithNilIndexEntries(t *testing.T) {
+	entries := append(repo.ChartVersions{nil}, rancherChartVersions("2.7.4", "2.7.5")...)
+	entries = append(entries, &repo.ChartVersion{URLs: []string{"https://releases.rancher.com/server-charts/stable/rancher-2.7.6.tgz"}})
+	client := Client{
+		versions: indexVersionSource{index: &repo.IndexFile{Entries: map[string]repo.ChartVersions{"rancher": entries}}},
+		policy:   PolicyPatchFirst,
+	}
+
+	if chartVersion, err := client.GetRancherChartForVersion("2.7.5"); err != nil || chartVersion.Version != "2.7.5" {
+		t.Errorf("GetRancherChartForVersion(2.7.5) = %v, %v, want chart version 2.7.5", chartVersion, err)
+	}
+	if _, err := client.GetRancherChartForVersion("2.7.6"); err == nil {
+		t.Error("GetRancherChartForVersion(2.7.6) of an entry without metadata expected an error")
+	}
+	if chartVersion, err := client.GetRancherChartForAppVersion("v2.7.4"); err != nil || chartVersion.Version != "2.7.4" {
+		t.Errorf("GetRancherChartForAppVersion(v2.7.4) = %v, %v, want chart version 2.7.4", chartVersion, err)
+	}
+	if _, err := client.GetRancherChartForAppVersion("v2.7.6"); err == nil {
+		t.Error("GetRancherChartForAppVersion(v2.7.6) of an entry without metadata expected an error")
+	}
+
+	chartVersions, err := client.ListRancherChartVersions()
+	if err != nil {
+		t.Fatalf("ListRancherChartVersions() unexpected error: %v", err)
+	}
+	listed := make([]string, 0, len(chartVersions))
+	for _, chartVersion := range chartVersions {
+		listed = append(listed, chartVersion.Version)
+	}
+	if strings.Join(listed, ",") != "2.7.4,2.7.5" {
+		t.Errorf("ListRancherChartVersions() = %v, want [2.7.4 2.7.5]", listed)
+	}
+	if next, err := client.GetNextSupportedRancherChartVersion("2.7.4"); err != nil || next != "2.7.5" {
+		t.Errorf("GetNextSupportedRancherChartVersion(2.7.4) = %s, %v, want 2.7.5", next, err)
+	}
+}
+
+func TestFindRancherReleases(t *testing.T) {
+	rancherRelease := func(name, namespace string) *release.Release {
+		return &release.Release{Name: name, Namespace: namespace, Chart: &chart.Chart{Metadata: &chart.Metadata{Name: "rancher"}}}
+	}
+	webhook := &release.Release{Name: "rancher-webhook", Namespace: "cattle-system", Chart: &chart.Chart{Metadata: &chart.Metadata{Name: "rancher-webhook"}}}
+
+	tests := []struct {
+		name       string
+		namespaces map[string][]*release.Release
+		namespace  string
+		scanAll    bool
+		// want is the found releases as name:namespace
+		want            []string
+		wantClusterWide int
+		wantErr         string
+	}{
+		{
+			name:            "found in cattle-system",
+			namespaces:      map[string][]*release.Release{"cattle-system": {webhook, rancherRelease("rancher", "cattle-system")}, "rancher-test": {rancherRelease("rancher", "rancher-test")}},
+			want:            []string{"rancher:cattle-system"},
+			wantClusterWide: 0,
+		},
+		{
+			name:            "falls back to all namespaces",
+			namespaces:      map[string][]*release.Release{"cattle-system": {webhook}, "rancher-test": {rancherRelease("rancher", "rancher-test")}},
+			want:            []string{"rancher:rancher-test"},
+			wantClusterWide: 1,
+		},
+		{
+			name:            "scans all namespaces for duplicates",
+			namespaces:      map[string][]*release.Release{"cattle-system": {rancherRelease("rancher", "cattle-system")}, "rancher-test": {rancherRelease("rancher", "rancher-test")}},
+			scanAll:         true,
+			want:            []string{"rancher:cattle-system", "rancher:rancher-test"},
+			wantClusterWide: 1,
+		},
+		{
+			name:            "scoped to a namespace",
+			namespaces:      map[string][]*release.Release{"cattle-system": {rancherRelease("rancher", "cattle-system")}, "rancher-test": {rancherRelease("rancher", "rancher-test")}},
+			namespace:       "rancher-test",
+			want:            []string{"rancher:rancher-test"},
+			wantClusterWide: 0,
+		},
+		{
+			name:            "not found",
+			namespaces:      map[string][]*release.Release{"cattle-system": {webhook}},
+			wantClusterWide: 1,
+			wantErr:         "rancher release could not be found",
+		},
+		{
+			name:            "cattle-system lookup fails",
+			namespaces:      nil,
+			wantClusterWide: 0,
+			wantErr:         "failed to look for the rancher release in [cattle-system]: forbidden",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clusterWide := 0
+			list := func(namespace string) ([]*release.Release, error) {
+				if tt.namespaces == nil {
+					return nil, errors.New("forbidden")
+				}
+				if namespace != "" {
+					return tt.namespaces[namespace], nil
+				}
+				clusterWide++
+				var all []*release.Release
+				for _, ns := range []string{"cattle-system", "rancher-test"} {
+					all = append(all, tt.namespaces[ns]...)
+				}
+				return all, nil
+			}
+
+			got, err := findRancherReleases(list, tt.namespace, tt.scanAll)
+			if clusterWide != tt.wantClusterWide {
+				t.Errorf("findRancherReleases() listed all namespaces %d times, want %d", clusterWide, tt.wantClusterWide)
+			}
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("findRancherReleases() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("findRancherReleases() unexpected error: %v", err)
+			}
+			found := make([]string, 0, len(got))
+			for _, rel := range got {
+				found = append(found, rel.Name+":"+rel.Namespace)
+			}
+			if strings.Join(found, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("findRancherReleases() = %v, want %v", found, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VerifyKubeconfig checks that the kubeconfig parses and selects a usable context, without contacting the cluster.
+func (c Client) VerifyKubeconfig() error {
+	if _, err := c.settings.RESTClientGetter().ToRESTConfig(); err != nil {
+		return fmt.Errorf("failed to load kubeconfig [%s]: %w", c.settings.KubeConfig, err)
+	}
+	return nil
+}
+
+// ServerVersion returns the kubernetes version of the cluster, which also confirms it is reachable with the
+// kubeconfig's credentials.
+func (c Client) ServerVersion() (string, error) {
+	discovery, err := c.settings.RESTClientGetter().ToDiscoveryClient()
+	if err != nil {
+		return "", err
+	}
+	version, err := discovery.ServerVersion()
+	if err != nil {
+		return "", fmt.Errorf("cluster is not reachable: %w", err)
+	}
+	return version.GitVersion, nil
+}
+
+// Deployment returns the live state of the Deployment name in namespace.
+func (c Client) Deployment(namespace, name string) (*appsv1.Deployment, error) {
+	clientset, err := c.actionConfig.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+	deployment, err := clientset.AppsV1().Deployments(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, permissionError(err, fmt.Sprintf("read deployment [%s]", name), namespace)
+	}
+	return deployment, nil
+}
+
+// ConfigMap returns the live state of the ConfigMap name in namespace.
+func (c Client) ConfigMap(namespace, name string) (*corev1.ConfigMap, error) {
+	clientset, err := c.actionConfig.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+	configMap, err := clientset.CoreV1().ConfigMaps(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, permissionError(err, fmt.Sprintf("read configmap [%s]", name), namespace)
+	}
+	return configMap, nil
+}
+
+// Secret returns the live state of the Secret name in namespace.
+func (c Client) Secret(namespace, name string) (*corev1.Secret, error) {
+	clientset, err := c.actionConfig.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, permissionError(err, fmt.Sprintf("read secret [%s]", name), namespace)
+	}
+	return secret, nil
+}
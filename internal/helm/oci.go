@@ -0,0 +1,108 @@
+package helm
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/rmweir/rancher-upgrader/internal/cleanup"
+	"helm.sh/helm/v3/pkg/chart"
+	cli2 "helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// ociVersionSource serves rancher chart versions from the tags of an OCI registry repository.
+type ociVersionSource struct {
+	// ref is the repository reference without the oci:// scheme, e.g. registry.example.com/rancher/rancher
+	ref            string
+	registryClient *registry.Client
+	// keyring verifies the provenance of pulled charts, nothing is verified when it is empty
+	keyring  string
+	settings *cli2.EnvSettings
+	// transport reaches the registry through an explicitly configured proxy, nil leaves it to the environment
+	transport *http.Transport
+}
+
+func newOCIVersionSource(ociRepo, keyring string, settings *cli2.EnvSettings, transport *http.Transport) (ociVersionSource, error) {
+	if !registry.IsOCI(ociRepo) {
+		return ociVersionSource{}, fmt.Errorf("invalid OCI repository [%s], must start with %s://", ociRepo, registry.OCIScheme)
+	}
+
+	clientOpts := []registry.ClientOption{
+		registry.ClientOptCredentialsFile(settings.RegistryConfig),
+		registry.ClientOptWriter(os.Stdout),
+	}
+	if transport != nil {
+		clientOpts = append(clientOpts, registry.ClientOptHTTPClient(&http.Client{Transport: transport}))
+	}
+	registryClient, err := registry.NewClient(clientOpts...)
+	if err != nil {
+		return ociVersionSource{}, err
+	}
+
+	return ociVersionSource{
+		ref:            strings.TrimSuffix(strings.TrimPrefix(ociRepo, registry.OCIScheme+"://"), "/"),
+		registryClient: registryClient,
+		keyring:        keyring,
+		settings:       settings,
+		transport:      transport,
+	}, nil
+}
+
+func (s ociVersionSource) AvailableVersions() ([]semver.Version, error) {
+	tags, err := s.registryClient.Tags(s.ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags for OCI repository [%s]: %w", s.ref, err)
+	}
+
+	var versions []semver.Version
+	for _, tag := range tags {
+		version, err := semver.Parse(tag)
+		if err != nil {
+			// registries can carry non-chart tags, only semver tags are chart versions
+			continue
+		}
+		versions = append(versions, version)
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("the OCI repository [%s] does not contain any rancher chart versions", s.ref)
+	}
+	return versions, nil
+}
+
+func (s ociVersionSource) Chart(version string) (*chart.Chart, error) {
+	result, err := s.registryClient.Pull(fmt.Sprintf("%s:%s", s.ref, version), registry.PullOptWithChart(true), registry.PullOptWithProv(s.keyring != ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull rancher chart [%s] from [%s]: %w", version, s.ref, err)
+	}
+
+	dir, err := os.MkdirTemp("", "rancher-upgrader-")
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup.Register(func() { os.RemoveAll(dir) })()
+
+	archivePath := filepath.Join(dir, fmt.Sprintf("rancher-%s.tgz", version))
+	if err := os.WriteFile(archivePath, result.Chart.Data, 0644); err != nil {
+		return nil, err
+	}
+	if s.keyring != "" {
+		if result.Prov == nil || len(result.Prov.Data) == 0 {
+			return nil, fmt.Errorf("rancher chart [%s] in [%s] has no provenance file, it cannot be verified", version, s.ref)
+		}
+		if err := os.WriteFile(archivePath+".prov", result.Prov.Data, 0644); err != nil {
+			return nil, err
+		}
+		verification, err := downloader.VerifyChart(archivePath, s.keyring)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify rancher chart [%s] with keyring [%s]: %w", version, s.keyring, err)
+		}
+		printVerification(version, verification)
+	}
+
+	return loadChartArchive(dir, archivePath, version, s.settings, s.registryClient, s.transport)
+}
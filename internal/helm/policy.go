@@ -0,0 +1,45 @@
+package helm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UpgradePolicy decides which version NextSupportedVersion picks when more than one newer version can be upgraded to.
+type UpgradePolicy string
+
+const (
+	// PolicyPatchFirst upgrades to the latest patch of the installed minor version before moving on to the next
+	// minor version. It is the default.
+	PolicyPatchFirst UpgradePolicy = "patch-first"
+	// PolicyMinorFirst upgrades straight to the latest patch of the next minor version when there is one, skipping
+	// the remaining patches of the installed minor version.
+	PolicyMinorFirst UpgradePolicy = "minor-first"
+	// PolicyNewest upgrades straight to the newest version of the installed major version, even when that skips minor
+	// versions, which rancher does not support.
+	PolicyNewest UpgradePolicy = "newest"
+)
+
+var upgradePolicies = []UpgradePolicy{PolicyPatchFirst, PolicyMinorFirst, PolicyNewest}
+
+// UpgradePolicyNames lists the valid policies, for flag usage and errors.
+func UpgradePolicyNames() string {
+	names := make([]string, len(upgradePolicies))
+	for i, policy := range upgradePolicies {
+		names[i] = string(policy)
+	}
+	return strings.Join(names, ", ")
+}
+
+// validateUpgradePolicy accepts the known policies, and no policy which means PolicyPatchFirst.
+func validateUpgradePolicy(policy UpgradePolicy) error {
+	if policy == "" {
+		return nil
+	}
+	for _, known := range upgradePolicies {
+		if policy == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown upgrade policy [%s], must be one of: %s", policy, UpgradePolicyNames())
+}
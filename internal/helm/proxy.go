@@ -0,0 +1,72 @@
+package helm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/rmweir/rancher-upgrader/internal/proxy"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// repoTransport returns the transport reaching the repo of entry through the proxy of config, or nil when config
+// leaves the proxy to the environment, which helm's getters honor on their own. Helm skips its own TLS setup for a
+// transport it is handed, so the certificates configured for entry are applied here instead. entry is nil for OCI
+// registries.
+func repoTransport(config proxy.Config, entry *repo.Entry) (*http.Transport, error) {
+	if !config.IsSet() {
+		return nil, nil
+	}
+	transport, err := config.Transport()
+	if err != nil {
+		return nil, err
+	}
+	// same as helm's getter, charts are already compressed
+	transport.DisableCompression = true
+	if entry == nil || (entry.CAFile == "" && entry.CertFile == "" && !entry.InsecureSkipTLSverify) {
+		return transport, nil
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: entry.InsecureSkipTLSverify}
+	if entry.CertFile != "" && entry.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(entry.CertFile, entry.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the client certificate of repo [%s]: %w", entry.Name, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if entry.CAFile != "" {
+		caPEM, err := os.ReadFile(entry.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA file of repo [%s]: %w", entry.Name, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("the CA file [%s] of repo [%s] holds no PEM certificates", entry.CAFile, entry.Name)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// httpGetters returns the getters for http and https repos, configured with options. They use transport when it is
+// set, and helm's default transport honoring the proxy environment variables otherwise.
+func httpGetters(transport *http.Transport, options ...getter.Option) getter.Providers {
+	if transport != nil {
+		options = append(options, getter.WithTransport(transport))
+	}
+	newGetter := getter.NewHTTPGetter
+	if len(options) != 0 {
+		newGetter = func(getterOptions ...getter.Option) (getter.Getter, error) {
+			return getter.NewHTTPGetter(append(getterOptions, options...)...)
+		}
+	}
+	return getter.Providers{getter.Provider{
+		Schemes: []string{"http", "https"},
+		New:     newGetter,
+	}}
+}
@@ -0,0 +1,64 @@
+package helm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/rmweir/rancher-upgrader/internal/proxy"
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+func TestUpdateRancherStableRepoThroughProxy(t *testing.T) {
+	for _, name := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy", "NO_PROXY", "no_proxy"} {
+		t.Setenv(name, "")
+	}
+	index := repo.NewIndexFile()
+	index.Entries = map[string]repo.ChartVersions{"rancher": rancherChartVersions("2.7.5")}
+	indexYAML, err := yaml.Marshal(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var proxied []string
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.Write(indexYAML)
+	}))
+	defer stub.Close()
+
+	entry := &repo.Entry{Name: rancherStableRepoName, URL: "http://releases.rancher.com/server-charts/stable"}
+	transport, err := repoTransport(proxy.Config{URL: stub.URL}, entry)
+	if err != nil {
+		t.Fatalf("repoTransport() unexpected error: %v", err)
+	}
+	cache := t.TempDir()
+	if err := updateRancherStableRepo(cache, entry, transport, 0); err != nil {
+		t.Fatalf("updateRancherStableRepo() unexpected error: %v", err)
+	}
+
+	if len(proxied) != 1 || proxied[0] != "http://releases.rancher.com/server-charts/stable/index.yaml" {
+		t.Errorf("stub proxy received %v, want the index request", proxied)
+	}
+	cached, err := repo.LoadIndexFile(filepath.Join(cache, helmpath.CacheIndexFile(rancherStableRepoName)))
+	if err != nil {
+		t.Fatalf("failed to load the cached index: %v", err)
+	}
+	if _, err := cached.Get("rancher", "2.7.5"); err != nil {
+		t.Errorf("cached index is not the proxied one: %v", err)
+	}
+}
+
+func TestRepoTransport(t *testing.T) {
+	transport, err := repoTransport(proxy.Config{}, &repo.Entry{Name: rancherStableRepoName, URL: rancherStableRepoURL})
+	if err != nil || transport != nil {
+		t.Errorf("repoTransport() without a proxy = %v, %v, want nil to leave the proxy to the environment", transport, err)
+	}
+
+	_, err = repoTransport(proxy.Config{URL: "http://proxy.example.com:3128"}, &repo.Entry{Name: rancherStableRepoName, CAFile: filepath.Join(t.TempDir(), "missing.pem")})
+	if err == nil {
+		t.Error("repoTransport() with a missing CA file expected an error")
+	}
+}
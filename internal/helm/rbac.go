@@ -0,0 +1,57 @@
+package helm
+
+import (
+	"errors"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// permissionError translates a forbidden error from the cluster into one naming what the kubeconfig's user was not
+// allowed to do, e.g. "upgrade release [rancher]", and which resource and namespace were denied. Other errors are
+// returned as is.
+func permissionError(err error, operation, namespace string) error {
+	if !apierrors.IsForbidden(err) {
+		return err
+	}
+
+	resource := "a resource"
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		if details := status.Status().Details; details != nil && details.Kind != "" {
+			resource = details.Kind
+			if details.Group != "" {
+				resource += "." + details.Group
+			}
+			resource = fmt.Sprintf("[%s]", resource)
+		}
+	}
+
+	scope := "across all namespaces"
+	if namespace != "" {
+		scope = fmt.Sprintf("in namespace [%s]", namespace)
+	}
+	return fmt.Errorf("insufficient permissions to %s: the kubeconfig's user may not access %s %s, grant it with a "+
+		"Role or ClusterRole binding: %w", operation, resource, scope, err)
+}
+
+// listReleasesClusterRole is the ClusterRole and binding letting a user list the helm releases of every namespace. helm
+// keeps each release in a Secret or ConfigMap of its namespace, depending on driver.
+func listReleasesClusterRole(driver string) string {
+	resource := "secrets"
+	if driver == "configmap" || driver == "configmaps" {
+		resource = "configmaps"
+	}
+	return fmt.Sprintf(`apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: rancher-upgrader-list-releases
+rules:
+- apiGroups: [""]
+  resources: ["%s"]
+  verbs: ["get", "list"]
+
+bound to the kubeconfig's user with:
+
+    kubectl create clusterrolebinding rancher-upgrader-list-releases --clusterrole=rancher-upgrader-list-releases --user=<user>`, resource)
+}
@@ -0,0 +1,62 @@
+package helm
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	cli2 "helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// RepoChartVersion is a rancher chart version served by one of the configured repositories.
+type RepoChartVersion struct {
+	Repo       string
+	URL        string
+	Version    string
+	AppVersion string
+}
+
+// FindRancherChartInOtherRepos returns the rancher charts with version as chart version or appVersion in the cached
+// indexes of every configured repository but rancher-stable, e.g. rancher-latest. The indexes are not updated, repos
+// that were never updated are skipped.
+func FindRancherChartInOtherRepos(version string) ([]RepoChartVersion, error) {
+	settings := cli2.New()
+	repoFile, err := repo.LoadFile(settings.RepositoryConfig)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	version = strings.TrimPrefix(version, "v")
+	var found []RepoChartVersion
+	for _, entry := range repoFile.Repositories {
+		if isRancherStableRepo(entry.URL) {
+			continue
+		}
+		index, err := repo.LoadIndexFile(filepath.Join(settings.RepositoryCache, helmpath.CacheIndexFile(entry.Name)))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the cached index of repo [%s]: %w", entry.Name, err)
+		}
+		for _, chartVersion := range index.Entries["rancher"] {
+			if chartVersion.Version != version && strings.TrimPrefix(chartVersion.AppVersion, "v") != version {
+				continue
+			}
+			found = append(found, RepoChartVersion{
+				Repo:       entry.Name,
+				URL:        entry.URL,
+				Version:    chartVersion.Version,
+				AppVersion: chartVersion.AppVersion,
+			})
+		}
+	}
+	return found, nil
+}
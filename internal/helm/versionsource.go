@@ -0,0 +1,225 @@
+package helm
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/enescakir/emoji"
+	"github.com/rmweir/rancher-upgrader/internal/cleanup"
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	cli2 "helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/provenance"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// VersionSource lists the available rancher chart versions and loads the chart for any of them.
+type VersionSource interface {
+	AvailableVersions() ([]semver.Version, error)
+	Chart(version string) (*chart.Chart, error)
+}
+
+// chartVersionLookup is implemented by version sources that carry repo index metadata for each version.
+type chartVersionLookup interface {
+	ChartVersion(version string) (*repo.ChartVersion, error)
+	ChartVersionForAppVersion(appVersion string) (*repo.ChartVersion, error)
+}
+
+// indexVersionSource serves rancher chart versions from a classic HTTP chart repository index.
+type indexVersionSource struct {
+	index    *repo.IndexFile
+	repoName string
+	// keyring verifies the provenance of downloaded charts, nothing is verified when it is empty
+	keyring  string
+	settings *cli2.EnvSettings
+	// username and password authenticate chart downloads, they are empty for anonymous repos
+	username string
+	password string
+	// transport reaches the repo through an explicitly configured proxy, nil leaves it to the environment
+	transport *http.Transport
+}
+
+// rancherEntries returns the rancher chart versions of the index, leaving out entries without metadata, which helm's
+// own index lookups would dereference.
+func (s indexVersionSource) rancherEntries() repo.ChartVersions {
+	var entries repo.ChartVersions
+	for _, chartVersion := range s.index.Entries["rancher"] {
+		if chartVersion != nil && chartVersion.Metadata != nil {
+			entries = append(entries, chartVersion)
+		}
+	}
+	return entries
+}
+
+func (s indexVersionSource) AvailableVersions() ([]semver.Version, error) {
+	entries := s.rancherEntries()
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("the repo index of [%s] contains a rancher chart with no versions, try refreshing the repo", s.repoName)
+	}
+	var versions []semver.Version
+	for _, chartVersion := range entries {
+		version, err := semver.New(chartVersion.Version)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, *version)
+	}
+	return versions, nil
+}
+
+func (s indexVersionSource) ChartVersion(version string) (*repo.ChartVersion, error) {
+	index := repo.IndexFile{Entries: map[string]repo.ChartVersions{"rancher": s.rancherEntries()}}
+	chartVersion, err := index.Get("rancher", version)
+	if err != nil {
+		return nil, err
+	}
+	// never trust helm to return an error for every missing version, callers dereference the result
+	if chartVersion == nil || chartVersion.Metadata == nil {
+		return nil, fmt.Errorf("rancher chart version [%s] not found in the repo index", version)
+	}
+	return chartVersion, nil
+}
+
+// ChartVersionForAppVersion returns the newest chart version shipping rancher appVersion.
+func (s indexVersionSource) ChartVersionForAppVersion(appVersion string) (*repo.ChartVersion, error) {
+	var found *repo.ChartVersion
+	for _, chartVersion := range s.rancherEntries() {
+		if strings.TrimPrefix(chartVersion.AppVersion, "v") != strings.TrimPrefix(appVersion, "v") {
+			continue
+		}
+		version, err := semver.Parse(chartVersion.Version)
+		if err != nil {
+			return nil, err
+		}
+		if found == nil || version.GT(semver.MustParse(found.Version)) {
+			found = chartVersion
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no rancher chart with appVersion [%s] found in the repo index", appVersion)
+	}
+	return found, nil
+}
+
+func (s indexVersionSource) Chart(version string) (*chart.Chart, error) {
+	dir, err := os.MkdirTemp("", "rancher-upgrader-")
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup.Register(func() { os.RemoveAll(dir) })()
+
+	chartDownloader := downloader.ChartDownloader{
+		Out:              os.Stdout,
+		Getters:          httpGetters(s.transport),
+		RepositoryConfig: s.settings.RepositoryConfig,
+		RepositoryCache:  s.settings.RepositoryCache,
+	}
+	if s.username != "" {
+		chartDownloader.Options = append(chartDownloader.Options, getter.WithBasicAuth(s.username, s.password))
+	}
+	if s.keyring != "" {
+		chartDownloader.Verify = downloader.VerifyAlways
+		chartDownloader.Keyring = s.keyring
+	}
+	archivePath, verification, err := chartDownloader.DownloadTo(s.repoName+"/rancher", version, dir)
+	if err != nil {
+		if s.keyring != "" {
+			return nil, fmt.Errorf("failed to download and verify rancher chart [%s] with keyring [%s]: %w", version, s.keyring, err)
+		}
+		return nil, fmt.Errorf("failed to download rancher chart [%s]: %w", version, err)
+	}
+	if verification != nil {
+		printVerification(version, verification)
+	}
+	if err := s.verifyDigest(archivePath, version); err != nil {
+		return nil, err
+	}
+
+	return loadChartArchive(dir, archivePath, version, s.settings, nil, s.transport)
+}
+
+// printVerification reports who signed a chart whose provenance was verified.
+func printVerification(version string, verification *provenance.Verification) {
+	signer := "an unknown key"
+	if verification.SignedBy != nil {
+		for name := range verification.SignedBy.Identities {
+			signer = name
+			break
+		}
+	}
+	fmt.Println(i18n.T(i18n.VerifiedProvenance, emoji.CheckMarkButton, version, signer))
+}
+
+// verifyDigest checks the downloaded archive against the digest the repo index records for version, which catches
+// corrupted and tampered downloads.
+func (s indexVersionSource) verifyDigest(archivePath, version string) error {
+	chartVersion, err := s.ChartVersion(version)
+	if err != nil {
+		return err
+	}
+	if chartVersion.Digest == "" {
+		fmt.Println(i18n.T(i18n.NoChartDigest, emoji.Warning, version))
+		return nil
+	}
+
+	digest, err := provenance.DigestFile(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to compute the digest of rancher chart [%s]: %w", version, err)
+	}
+	if !strings.EqualFold(digest, strings.TrimPrefix(chartVersion.Digest, "sha256:")) {
+		return fmt.Errorf("downloaded rancher chart [%s] has digest [%s], but the repo index records [%s], the download is "+
+			"corrupted or was tampered with", version, digest, chartVersion.Digest)
+	}
+	return nil
+}
+
+// loadChartArchive expands the chart archive into dir and rebuilds its dependencies from the chart's Chart.lock, so
+// the upgrade never applies stale subcharts.
+func loadChartArchive(dir, archivePath, version string, settings *cli2.EnvSettings, registryClient *registry.Client, transport *http.Transport) (*chart.Chart, error) {
+	if err := chartutil.ExpandFile(dir, archivePath); err != nil {
+		return nil, err
+	}
+	chartPath := filepath.Join(dir, "rancher")
+
+	targetChart, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(targetChart.Metadata.Dependencies) == 0 {
+		return targetChart, nil
+	}
+
+	fmt.Println(i18n.T(i18n.ResolvingDependencies, version))
+	manager := downloader.Manager{
+		Out:              os.Stdout,
+		ChartPath:        chartPath,
+		SkipUpdate:       true,
+		Getters:          httpGetters(transport),
+		RegistryClient:   registryClient,
+		RepositoryConfig: settings.RepositoryConfig,
+		RepositoryCache:  settings.RepositoryCache,
+	}
+	if err := manager.Build(); err != nil {
+		return nil, fmt.Errorf("failed to resolve dependencies for rancher chart [%s]: %w", version, err)
+	}
+
+	// reload so the rebuilt charts/ directory is picked up
+	targetChart, err = loader.Load(chartPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := action.CheckDependencies(targetChart, targetChart.Metadata.Dependencies); err != nil {
+		return nil, fmt.Errorf("rancher chart [%s] has unresolved dependencies: %w", version, err)
+	}
+	return targetChart, nil
+}
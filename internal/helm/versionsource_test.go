@@ -0,0 +1,107 @@
+package helm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/provenance"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+func TestVerifyDigest(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "rancher-2.7.5.tgz")
+	if err := os.WriteFile(archivePath, []byte("rancher chart archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	digest, err := provenance.DigestFile(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const mismatched = "0000000000000000000000000000000000000000000000000000000000000000"
+
+	tests := []struct {
+		name    string
+		digest  string
+		version string
+		wantErr string
+	}{
+		{name: "matching", digest: digest, version: "2.7.5"},
+		{name: "matching with algorithm prefix", digest: "sha256:" + strings.ToUpper(digest), version: "2.7.5"},
+		{name: "no digest recorded", digest: "", version: "2.7.5"},
+		{
+			name:    "mismatched",
+			digest:  mismatched,
+			version: "2.7.5",
+			wantErr: "downloaded rancher chart [2.7.5] has digest [" + digest + "], but the repo index records [" + mismatched + "], " +
+				"the download is corrupted or was tampered with",
+		},
+		{
+			name:    "version not in index",
+			digest:  digest,
+			version: "2.7.6",
+			wantErr: "no chart version found for rancher-2.7.6",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chartVersions := rancherChartVersions("2.7.5")
+			chartVersions[0].Digest = tt.digest
+			source := indexVersionSource{index: &repo.IndexFile{Entries: map[string]repo.ChartVersions{"rancher": chartVersions}}}
+
+			err := source.verifyDigest(archivePath, tt.version)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("verifyDigest() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verifyDigest() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestChartVersion(t *testing.T) {
+	withoutMetadata := &repo.ChartVersion{URLs: []string{"https://releases.rancher.com/server-charts/stable/rancher-2.7.4.tgz"}}
+
+	tests := []struct {
+		name    string
+		entries repo.ChartVersions
+		version string
+		wantErr string
+	}{
+		{name: "found", entries: rancherChartVersions("2.7.4", "2.7.5"), version: "2.7.5"},
+		{name: "found next to nil entries", entries: append(repo.ChartVersions{nil, withoutMetadata}, rancherChartVersions("2.7.5")...), version: "2.7.5"},
+		{name: "not found", entries: rancherChartVersions("2.7.4", "2.7.5"), version: "2.7.6", wantErr: "no chart version found for rancher-2.7.6"},
+		{
+			name:    "not found next to nil entries",
+			entries: append(rancherChartVersions("2.7.5"), nil, withoutMetadata),
+			version: "2.7.6",
+			wantErr: "no chart version found for rancher-2.7.6",
+		},
+		{name: "only nil entries", entries: repo.ChartVersions{nil, withoutMetadata}, version: "2.7.4", wantErr: "no chart version found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := indexVersionSource{index: &repo.IndexFile{Entries: map[string]repo.ChartVersions{"rancher": tt.entries}}}
+			chartVersion, err := source.ChartVersion(tt.version)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("ChartVersion() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ChartVersion() unexpected error: %v", err)
+			}
+			if chartVersion.Version != tt.version {
+				t.Errorf("ChartVersion() = %s, want %s", chartVersion.Version, tt.version)
+			}
+		})
+	}
+}
@@ -0,0 +1,385 @@
+package i18n
+
+const (
+	Welcome                    Message = "welcome"
+	RunStarted                 Message = "run-started"
+	RunFinished                Message = "run-finished"
+	CleaningUp                 Message = "cleaning-up"
+	DetectingReleases          Message = "detecting-releases"
+	FoundRelease               Message = "found-release"
+	FoundMultipleReleases      Message = "found-multiple-releases"
+	SelectReleasePrompt        Message = "select-release-prompt"
+	ConfirmRelease             Message = "confirm-release"
+	ChartVersion               Message = "chart-version"
+	ChartAndAppVersion         Message = "chart-and-app-version"
+	ChartVersionCreated        Message = "chart-version-created"
+	CurrentVersionMarker       Message = "current-version-marker"
+	NextVersionMarker          Message = "next-version-marker"
+	NoChartVersionsAvailable   Message = "no-chart-versions-available"
+	UpToDate                   Message = "up-to-date"
+	UpgradedMeanwhile          Message = "upgraded-meanwhile"
+	SavedValues                Message = "saved-values"
+	SavedSnapshot              Message = "saved-snapshot"
+	DumpedRelease              Message = "dumped-release"
+	NextAvailableUpdate        Message = "next-available-update"
+	LocalChartUpdate           Message = "local-chart-update"
+	BasingOnRevision           Message = "basing-on-revision"
+	LatestRevisionFailed       Message = "latest-revision-failed"
+	OfferDeployedRevision      Message = "offer-deployed-revision"
+	ReviewingSinceLastUpgrade  Message = "reviewing-since-last-upgrade"
+	ReviewedSinceLastUpgrade   Message = "reviewed-since-last-upgrade"
+	NoPreviousUpgrade          Message = "no-previous-upgrade"
+	LastChangeWasDowngrade     Message = "last-change-was-downgrade"
+	ContinueWithFailedRevision Message = "continue-with-failed-revision"
+	UpgradePlan                Message = "upgrade-plan"
+	TargetUpgradePlan          Message = "target-upgrade-plan"
+	ResolvedAppVersion         Message = "resolved-app-version"
+	UpgradeHop                 Message = "upgrade-hop"
+	DryRunComplete             Message = "dry-run-complete"
+	UpgradeSucceeded           Message = "upgrade-succeeded"
+	ChartNotesHeader           Message = "chart-notes-header"
+	DryRunChartNotesHeader     Message = "dry-run-chart-notes-header"
+	ContinuePrompt             Message = "continue-prompt"
+	ConfirmPhrasePrompt        Message = "confirm-phrase-prompt"
+	ConfirmPhraseMismatch      Message = "confirm-phrase-mismatch"
+	InvalidInput               Message = "invalid-input"
+	SimulatingSession          Message = "simulating-session"
+	OfferAddRepo               Message = "offer-add-repo"
+	AddedRepo                  Message = "added-repo"
+	OtherReposSearchFailed     Message = "other-repos-search-failed"
+	NotInOtherRepos            Message = "not-in-other-repos"
+	FoundInOtherRepos          Message = "found-in-other-repos"
+	OtherRepoChart             Message = "other-repo-chart"
+	MissingRepoRemediation     Message = "missing-repo-remediation"
+	CurrentVersionEndOfLife    Message = "current-version-end-of-life"
+	TargetVersionEndOfLife     Message = "target-version-end-of-life"
+	WebhookNotFound            Message = "webhook-not-found"
+	WebhookUpgradedByRancher   Message = "webhook-upgraded-by-rancher"
+	WebhookMismatch            Message = "webhook-mismatch"
+	LintingChart               Message = "linting-chart"
+	LintPassed                 Message = "lint-passed"
+	LintFailed                 Message = "lint-failed"
+	RunningPostUpgradeCheck    Message = "running-post-upgrade-check"
+	PostUpgradeCheckPassed     Message = "post-upgrade-check-passed"
+	PostUpgradeCheckFailed     Message = "post-upgrade-check-failed"
+	OfferRollback              Message = "offer-rollback"
+	RolledBack                 Message = "rolled-back"
+	ConfirmRollback            Message = "confirm-rollback"
+
+	CurrentOverrideValues   Message = "current-override-values"
+	NoOverrideValues        Message = "no-override-values"
+	ShowAllValuesPrompt     Message = "show-all-values-prompt"
+	ValuesToApply           Message = "values-to-apply"
+	CoalesceValuesFailed    Message = "coalesce-values-failed"
+	ValuesMenu              Message = "values-menu"
+	ValuesMenuContinue      Message = "values-menu-continue"
+	ValuesMenuConfigure     Message = "values-menu-configure"
+	ValuesMenuGuided        Message = "values-menu-guided"
+	GuidedValuesUnavailable Message = "guided-values-unavailable"
+	GuidedValuesIntro       Message = "guided-values-intro"
+	GuidedValuePrompt       Message = "guided-value-prompt"
+	GuidedValueInvalid      Message = "guided-value-invalid"
+	GuidedValueNeedsFile    Message = "guided-value-needs-file"
+	EnterValuesFilePath     Message = "enter-values-file-path"
+	RetryValuesFile         Message = "retry-values-file"
+	MergedValuesFiles       Message = "merged-values-files"
+	UsingClusterValues      Message = "using-cluster-values"
+	KeepingOverrideValues   Message = "keeping-override-values"
+
+	ReleaseCount                   Message = "release-count"
+	ReviewChangesIntro             Message = "review-changes-intro"
+	TUIHelp                        Message = "tui-help"
+	TUIPendingAcknowledgements     Message = "tui-pending-acknowledgements"
+	TUIUnavailable                 Message = "tui-unavailable"
+	NotesLegend                    Message = "notes-legend"
+	SectionBugfixes                Message = "section-bugfixes"
+	SectionBehaviorChanges         Message = "section-behavior-changes"
+	SectionKnownIssues             Message = "section-known-issues"
+	SectionInstallNotes            Message = "section-install-notes"
+	Published                      Message = "published"
+	NotPublished                   Message = "not-published"
+	DraftReleaseNotes              Message = "draft-release-notes"
+	ReleaseLinks                   Message = "release-links"
+	PrereleaseNotes                Message = "prerelease-notes"
+	NotesCollapsedBeforeSince      Message = "notes-collapsed-before-since"
+	NotesSkipped                   Message = "notes-skipped"
+	NotesNotFetched                Message = "notes-not-fetched"
+	UnfetchedNotesSummary          Message = "unfetched-notes-summary"
+	IgnoringNotesErrors            Message = "ignoring-notes-errors"
+	BugfixesHeader                 Message = "bugfixes-header"
+	NoBugfixes                     Message = "no-bugfixes"
+	BugfixesReadMore               Message = "bugfixes-read-more"
+	BehaviorChangesHeader          Message = "behavior-changes-header"
+	BreakingChangesCount           Message = "breaking-changes-count"
+	AcknowledgeBreakingChange      Message = "acknowledge-breaking-change"
+	AppVersionDowngrade            Message = "app-version-downgrade"
+	AcknowledgeAppVersionDowngrade Message = "acknowledge-app-version-downgrade"
+	AllowingAppVersionDowngrade    Message = "allowing-app-version-downgrade"
+	NoBehaviorChanges              Message = "no-behavior-changes"
+	KnownIssuesHeader              Message = "known-issues-header"
+	KnownIssueProgress             Message = "known-issue-progress"
+	AcknowledgeKnownIssue          Message = "acknowledge-known-issue"
+	AcknowledgeIssueNumber         Message = "acknowledge-issue-number"
+	NoKnownIssues                  Message = "no-known-issues"
+	ListingKnownIssues             Message = "listing-known-issues"
+	KnownIssuesInRange             Message = "known-issues-in-range"
+	NoKnownIssuesInRange           Message = "no-known-issues-in-range"
+	KnownIssueGateFailed           Message = "known-issue-gate-failed"
+	BehaviorAckGateFailed          Message = "behavior-ack-gate-failed"
+	InstallNotesHeader             Message = "install-notes-header"
+	NoInstallNotes                 Message = "no-install-notes"
+	SkippedEmptyReleases           Message = "skipped-empty-releases"
+	QuietNotesSummary              Message = "quiet-notes-summary"
+	SectionOutOfOrder              Message = "section-out-of-order"
+	ImpactHeader                   Message = "impact-header"
+	NoImpactFound                  Message = "no-impact-found"
+
+	NoResourceChanges           Message = "no-resource-changes"
+	ResourceChangesHeader       Message = "resource-changes-header"
+	ConfigChangesHeader         Message = "config-changes-header"
+	NoConfigChanges             Message = "no-config-changes"
+	SecretReplaced              Message = "secret-replaced"
+	UpgradeHooksHeader          Message = "upgrade-hooks-header"
+	NoUpgradeHooks              Message = "no-upgrade-hooks"
+	DefaultValuesUnchanged      Message = "default-values-unchanged"
+	DefaultValuesChanged        Message = "default-values-changed"
+	DefaultValuesNote           Message = "default-values-note"
+	MetadataUnchanged           Message = "metadata-unchanged"
+	MetadataChanged             Message = "metadata-changed"
+	ValidateHeader              Message = "validate-header"
+	CheckPassed                 Message = "check-passed"
+	CheckFailed                 Message = "check-failed"
+	CheckSkipped                Message = "check-skipped"
+	ValidatePassed              Message = "validate-passed"
+	ValidateFailed              Message = "validate-failed"
+	CheckKubeconfig             Message = "check-kubeconfig"
+	CheckCluster                Message = "check-cluster"
+	CheckRelease                Message = "check-release"
+	CheckRepo                   Message = "check-repo"
+	CheckTarget                 Message = "check-target"
+	CheckDependsOn              Message = "check-depends-on"
+	SetupHeader                 Message = "setup-header"
+	SetupRepositoryConfig       Message = "setup-repository-config"
+	NoRepositoryConfig          Message = "no-repository-config"
+	OfferCreateRepositoryConfig Message = "offer-create-repository-config"
+	NoKubeconfigPassed          Message = "no-kubeconfig-passed"
+	SetupIncomplete             Message = "setup-incomplete"
+	SetupComplete               Message = "setup-complete"
+
+	SkippingRepoUpdate    Message = "skipping-repo-update"
+	IndexNotDated         Message = "index-not-dated"
+	UsingIndexGeneratedAt Message = "using-index-generated-at"
+	IndexTooOld           Message = "index-too-old"
+	VerifyingRepo         Message = "verifying-repo"
+	RepoFound             Message = "repo-found"
+	UpdatingRepoIndex     Message = "updating-repo-index"
+	SkipsMinorVersions    Message = "skips-minor-versions"
+	MinorLinePruned       Message = "minor-line-pruned"
+	RetryingUpgrade       Message = "retrying-upgrade"
+	VerifiedProvenance    Message = "verified-provenance"
+	NoChartDigest         Message = "no-chart-digest"
+	ResolvingDependencies Message = "resolving-dependencies"
+
+	ExplainDetection        Message = "explain-detection"
+	ExplainVersionSelection Message = "explain-version-selection"
+	ExplainNoteReview       Message = "explain-note-review"
+	ExplainValues           Message = "explain-values"
+	ExplainUpgrade          Message = "explain-upgrade"
+)
+
+var english = map[Message]string{
+	Welcome:                    "Welcome to rancher upgrader %v",
+	RunStarted:                 "Run ID: %s",
+	RunFinished:                "Finished run [%s].",
+	CleaningUp:                 "Interrupted, cleaning up...",
+	DetectingReleases:          "%v Detecting rancher releases...",
+	FoundRelease:               "Found rancher release [%s] in namespace [%s] at %s",
+	FoundMultipleReleases:      "Found %d rancher releases:",
+	SelectReleasePrompt:        "Select the rancher release to use by entering its corresponding number: ",
+	ConfirmRelease:             "Is %s:%s the rancher release you would like to upgrade?",
+	ChartVersion:               "chart version [%s]",
+	ChartAndAppVersion:         "chart version [%s] (rancher [%s])",
+	ChartVersionCreated:        "created %s",
+	CurrentVersionMarker:       "<- current",
+	NextVersionMarker:          "<- next",
+	NoChartVersionsAvailable:   "No rancher chart versions are available in the repo.",
+	UpToDate:                   "%v Your rancher install is already up to date!",
+	UpgradedMeanwhile:          "%v Release [%s] was upgraded to [%s] in the meantime, there is nothing left to upgrade.",
+	SavedValues:                "Saved the applied override values to [%s].",
+	SavedSnapshot:              "%v Saved a snapshot of release [%s] to [%s].",
+	DumpedRelease:              "%v Wrote release [%s] to [%s], share it when reporting a bug. Review it first, its config is only redacted for keys matching --redact-keys.",
+	NextAvailableUpdate:        "Next available update from %s to %s.",
+	LocalChartUpdate:           "Update from the chart at [%s] from %s to %s.",
+	BasingOnRevision:           "Basing the upgrade on revision [%d] at %s, status [%s].",
+	LatestRevisionFailed:       "%v The latest revision [%d] of release [%s] failed.",
+	OfferDeployedRevision:      "Revision [%d] at %s was the last one deployed. Base the upgrade on it instead? ",
+	ReviewingSinceLastUpgrade:  "The release was last upgraded from revision [%d] at %s, reviewing the notes of every release since then up to the installed %s.",
+	ReviewedSinceLastUpgrade:   "%v Reviewed the notes from [%s] up to the installed [%s], nothing was upgraded.",
+	NoPreviousUpgrade:          "The history of release [%s] records no other chart version than the installed [%s], there is nothing to review.",
+	LastChangeWasDowngrade:     "%v Revision [%d] was at chart version [%s], newer than the installed [%s], the release was rolled back or downgraded since. There are no notes to review.",
+	ContinueWithFailedRevision: "Continue with the failed revision [%d]? ",
+	UpgradePlan:                "The newest supported version is [%s]. Reaching it takes %d upgrade(s):",
+	TargetUpgradePlan:          "Reaching the target version [%s] takes %d upgrade(s):",
+	ResolvedAppVersion:         "Rancher [%s] ships with chart version [%s].",
+	UpgradeHop:                 "Upgrade %d of %d: [%s] -> [%s]",
+	DryRunComplete:             "%v Dry run complete, rancher would be upgraded from %s to %s. Re-run with --dry-run=false to apply.",
+	UpgradeSucceeded:           "%v%v You have succesfully upgraded rancher from %s to %s!",
+	ChartNotesHeader:           "Here are the rancher chart's notes for the upgraded release:",
+	DryRunChartNotesHeader:     "Here are the notes the rancher chart would show after the upgrade:",
+	ContinuePrompt:             "Continue? [y/n]",
+	ConfirmPhrasePrompt:        "This upgrades release [%s] in namespace [%s] for real. Type [%s] to confirm: ",
+	ConfirmPhraseMismatch:      "The typed phrase does not match, the upgrade was not started.",
+	InvalidInput:               "Invalid input, try again.",
+	SimulatingSession:          "Simulating the session recorded in [%s], nothing will be changed.",
+	OfferAddRepo:               "Run [%s] now? ",
+	AddedRepo:                  "%v Added the rancher-stable repo.",
+	OtherReposSearchFailed:     "%v Searching the other helm repos failed: %v",
+	NotInOtherRepos:            "None of the other configured helm repos serve rancher [%s] either.",
+	FoundInOtherRepos:          "Rancher [%s] is served by these other configured helm repos:",
+	OtherRepoChart:             "  %s (%s): chart version [%s], rancher [%s]",
+	MissingRepoRemediation:     "The rancher-stable chart repository is not configured. Add it with:",
+	CurrentVersionEndOfLife:    "%v The installed version [%s] reached its end of life on %s and no longer receives fixes.",
+	TargetVersionEndOfLife:     "%v The target version [%s] reached its end of life on %s, consider upgrading further to a supported version.",
+	WebhookNotFound:            "No %s release was found, its compatibility with the target version cannot be checked.",
+	WebhookUpgradedByRancher:   "%s [%s] matches the installed rancher, rancher upgrades it to a version matching [%s] (%s) after the upgrade.",
+	WebhookMismatch:            "%v %s [%s] works with neither the installed rancher [%s] nor the target [%s], which needs %s. A mismatched webhook can reject changes across the cluster, fix it before upgrading.",
+	LintingChart:               "Rendering rancher chart [%s] with the proposed values...",
+	LintPassed:                 "%v The chart rendered %d resource(s) without errors.",
+	LintFailed:                 "%v The chart failed to render: %v",
+	RunningPostUpgradeCheck:    "Running post-upgrade check [%s]...",
+	PostUpgradeCheckPassed:     "%v Post-upgrade check passed.",
+	PostUpgradeCheckFailed:     "%v Post-upgrade check failed: %v",
+	OfferRollback:              "Roll release [%s] back to revision [%d]? ",
+	RolledBack:                 "Rolled release [%s] back to revision [%d].",
+	ConfirmRollback:            "Roll release [%s:%s] back from revision [%d] at %s to revision [%d] at %s? ",
+
+	CurrentOverrideValues:   "Here are the current chart override values:",
+	NoOverrideValues:        "There are currently no chart override values configured.",
+	ShowAllValuesPrompt:     "Would you like to see all configured values, including defaults? [y/n]",
+	ValuesToApply:           "Values to be applied to rancher chart:",
+	CoalesceValuesFailed:    "%v The chart default values could not be combined with the override values, so they cannot be shown. The override values above are still applied as they are.",
+	ValuesMenu:              "Select one of the following options by entering their corresponding number",
+	ValuesMenuContinue:      "1. Continue with displayed override chart values",
+	ValuesMenuConfigure:     "2. Configure different override values",
+	ValuesMenuGuided:        "3. Set the override values one by one, guided by the chart's values.schema.json",
+	GuidedValuesUnavailable: "%v The target chart ships no values.schema.json, --guided-values is ignored and values are configured with a file.",
+	GuidedValuesIntro:       "Enter a value for each setting, or press enter to keep the value shown in brackets.",
+	GuidedValuePrompt:       "%s (%s) [%s]: ",
+	GuidedValueInvalid:      "Invalid value for %s: %v",
+	GuidedValueNeedsFile:    "Skipping %s (%s), set it with a values file.",
+	EnterValuesFilePath:     "Enter the paths of one or more comma separated values.yaml files, or nothing to cancel: ",
+	RetryValuesFile:         "Enter a corrected path, or nothing to keep the current values. %d attempt(s) left.",
+	MergedValuesFiles:       "Merged %d values files in order, later files win: %s",
+	UsingClusterValues:      "Using the override values from %s merged over the current ones of the release, later ones win.",
+	KeepingOverrideValues:   "Keeping these override values, --yes is set.",
+
+	ReleaseCount:                   "There have been %d releases between rancher [%s] and rancher [%s] (inclusive).",
+	ReviewChangesIntro:             "Let's go over the changes that have happened throughout these releases",
+	TUIHelp:                        "up/down or j/k move, page up/down scroll, space ticks a known issue or breaking change, enter confirms, q stops the upgrade",
+	TUIPendingAcknowledgements:     "%d known issue(s) and breaking change(s) are not ticked yet, tick them with space before confirming",
+	TUIUnavailable:                 "%v --tui needs a terminal for both input and output, falling back to the prompts.",
+	NotesLegend:                    "Colors: %s",
+	SectionBugfixes:                "bug fixes",
+	SectionBehaviorChanges:         "behavior changes",
+	SectionKnownIssues:             "known issues",
+	SectionInstallNotes:            "install/upgrade notes",
+	Published:                      "published %s",
+	NotPublished:                   "not published yet",
+	DraftReleaseNotes:              "%v Release [%s] is still a draft on GitHub, its notes may be incomplete and can change before it is published.",
+	ReleaseLinks:                   "Release notes: %s, full changelog: %s",
+	PrereleaseNotes:                "%v Release [%s] is marked as a prerelease on GitHub, it is not meant for production installs.",
+	NotesCollapsedBeforeSince:      "Skipping the notes of release [%s], it was published before %s.",
+	NotesSkipped:                   "%v Skipping the release notes of the releases after [%s] up to [%s] as --skip-notes is set, make sure they were reviewed.",
+	NotesNotFetched:                "%v The notes of release [%s] could not be fetched, review them at %s",
+	UnfetchedNotesSummary:          "%v Could not fetch the notes of %s, review them manually before continuing:",
+	IgnoringNotesErrors:            "Continuing without them as --ignore-notes-errors is set, the run will not fail because of them.",
+	BugfixesHeader:                 "Here are some of the bugfixes introduced by release [%s]",
+	NoBugfixes:                     "We did not find any bugfixes, we recommend consulting the release page for more info.",
+	BugfixesReadMore:               "If you would like to read more about bugfixes in release [%s], visit %s",
+	BehaviorChangesHeader:          "Here are the rancher behavior changes introduced by release [%s]",
+	BreakingChangesCount:           "%v %d of them look breaking and have to be acknowledged one by one, the others are listed for information.",
+	AcknowledgeBreakingChange:      "Continue if you acknowledge this breaking change. ",
+	AppVersionDowngrade:            "%v Chart version [%s] ships rancher [%s], older than the installed chart version [%s] with rancher [%s]. The chart is likely mispackaged and upgrading would downgrade rancher.",
+	AcknowledgeAppVersionDowngrade: "Continue only if you are sure the rancher downgrade is intended. ",
+	AllowingAppVersionDowngrade:    "Continuing with the rancher downgrade as --allow-app-version-downgrade is set.",
+	NoBehaviorChanges:              "We did not find any behavior changes for release [%s].",
+	KnownIssuesHeader:              "Let's review the %d known issue(s) in release [%s]",
+	KnownIssueProgress:             "Known issue %d of %d:",
+	AcknowledgeKnownIssue:          "Continue if you acknowledge this issue and still wish to proceed. ",
+	AcknowledgeIssueNumber:         "Enter the issue number [%s] if you acknowledge this issue and still wish to proceed, or n to stop: ",
+	NoKnownIssues:                  "We did not find any known issues for release [%s].",
+	ListingKnownIssues:             "%v Collecting the known issues of the %d releases after [%s] up to [%s]...",
+	KnownIssuesInRange:             "%d known issues in the releases after [%s] up to [%s]:",
+	NoKnownIssuesInRange:           "No known issues in the releases after [%s] up to [%s].",
+	KnownIssueGateFailed:           "%v %d known issue(s) in the upgraded releases match --fail-on-known-issue [%s]:",
+	BehaviorAckGateFailed:          "%v %d behavior change(s) in the upgraded releases are not acknowledged in --acked-behavior-changes:",
+	InstallNotesHeader:             "Here are the install/upgrade notes for release [%s]",
+	NoInstallNotes:                 "We did not find any install/upgrade notes for release [%s].",
+	SkippedEmptyReleases:           "Skipped %d release(s) with no relevant notes: %s",
+	QuietNotesSummary:              "Reviewed the notes of %d release(s) from [%s] to [%s] without printing them, %d known issue(s) acknowledged. Re-run without --quiet or --interactive=false to read them.",
+	SectionOutOfOrder:              "%v The [%s] section of the release notes does not come before the [%s] section, skipping it.",
+	ImpactHeader:                   "%v Estimated impact: %d install/upgrade note(s) mention downtime or disruption, plan a maintenance window accordingly:",
+	NoImpactFound:                  "Estimated impact: no install/upgrade notes mention downtime or disruption. This is based on keywords, read the notes above to be sure.",
+
+	NoResourceChanges:           "The upgrade does not change any rendered resources.",
+	ResourceChangesHeader:       "Here are the resource changes the upgrade would apply:",
+	ConfigChangesHeader:         "Here are the ConfigMap and Secret changes, Secret values are hidden:",
+	NoConfigChanges:             "The upgrade does not change any ConfigMaps or Secrets.",
+	SecretReplaced:              "%v This Secret would be deleted or recreated, anything relying on its current contents will see new ones.",
+	UpgradeHooksHeader:          "Here are the helm hooks that would run during the upgrade:",
+	NoUpgradeHooks:              "No helm hooks would run during the upgrade.",
+	DefaultValuesUnchanged:      "The default chart values did not change between chart version [%s] and [%s].",
+	DefaultValuesChanged:        "Here are the default chart values that changed between chart version [%s] and [%s]:",
+	DefaultValuesNote:           "Default values only apply to keys you have not overridden.",
+	MetadataUnchanged:           "The chart metadata did not change between chart version [%s] and [%s].",
+	MetadataChanged:             "Here is the chart metadata that changed between chart version [%s] and [%s]:",
+	ValidateHeader:              "Checking whether rancher is ready to upgrade, no release notes are fetched and nothing is changed:",
+	CheckPassed:                 "%v %s: %s",
+	CheckFailed:                 "%v %s: %s",
+	CheckSkipped:                "%v %s: skipped, %s",
+	ValidatePassed:              "%v All %d checks passed, rancher is ready to upgrade.",
+	ValidateFailed:              "%v %d of %d checks failed, resolve them before upgrading.",
+	CheckKubeconfig:             "Kubeconfig parses",
+	CheckCluster:                "Cluster reachable",
+	CheckRelease:                "Rancher release found",
+	CheckRepo:                   "Chart repository configured",
+	CheckTarget:                 "Target version resolvable",
+	CheckDependsOn:              "requires [%s]",
+	SetupHeader:                 "%v Checking the prerequisites of rancher-upgrader, you are asked before anything is changed:",
+	SetupRepositoryConfig:       "Helm repositories file",
+	NoRepositoryConfig:          "%v There is no helm repositories file at [%s] yet.",
+	OfferCreateRepositoryConfig: "Create an empty one now? ",
+	NoKubeconfigPassed:          "no kubeconfig passed, re-run with --kubeconfig or --kubeconfig-data",
+	SetupIncomplete:             "%v %d prerequisites are still missing, resolve them before upgrading.",
+	SetupComplete:               "%v All prerequisites are in place, check the upgrade with: rancher-upgrade validate",
+
+	SkippingRepoUpdate:    "Skipping repo update, using the cached rancher-stable repo index.",
+	IndexNotDated:         "The rancher-stable repo index does not record when it was generated.",
+	UsingIndexGeneratedAt: "Using rancher-stable repo index generated at %s.",
+	IndexTooOld: "%v The rancher-stable repo index is %s old, older than --max-index-age %s. The newest rancher " +
+		"versions may be missing, check that the repo update succeeded.",
+	VerifyingRepo:      "Verifying rancher-stable repo exists...",
+	RepoFound:          "%v Rancher-stable repo found!",
+	UpdatingRepoIndex:  "Updating the %s repo index...",
+	SkipsMinorVersions: "%v Upgrading from [%s] straight to [%s] skips minor versions, which rancher does not support.",
+	MinorLinePruned: "%v No [%d.%d.x] versions are available anymore, so the latest patch of the line cannot be " +
+		"reached first. Offering [%s] of the next minor version instead.",
+	RetryingUpgrade:       "%v Upgrade attempt %d of %d failed with a transient error: %v\nRetrying in %s...",
+	VerifiedProvenance:    "%v Verified the provenance of rancher chart [%s], signed by %s.",
+	NoChartDigest:         "%v The repo index records no digest for rancher chart [%s], the download cannot be verified.",
+	ResolvingDependencies: "Resolving dependencies for rancher chart [%s]...",
+
+	ExplainDetection: "Rancher is installed as a helm release of the rancher chart. The helm releases in the cluster are " +
+		"listed to find it, along with its chart version and the override values it was installed with.",
+	ExplainVersionSelection: "Rancher supports upgrading to a newer patch of the installed minor version or to the next " +
+		"minor version, minor versions cannot be skipped. The next version is the newest one following that rule, " +
+		"taken from the available rancher chart versions.",
+	ExplainNoteReview: "The release notes of every release between the installed and the target version are reviewed, " +
+		"since changes pile up across releases. Known issues can break an upgrade or need a workaround, so each one " +
+		"has to be acknowledged before continuing.",
+	ExplainValues: "The upgrade keeps the override values the release was installed with, applied over the defaults " +
+		"of the new chart version. New chart versions can add, rename or remove values, so check them before continuing.",
+	ExplainUpgrade: "The release is upgraded with helm to the new chart version. The rancher pods are replaced in a " +
+		"rolling update, after which rancher updates the agents in the downstream clusters. A dry run renders the " +
+		"upgrade without applying it.",
+}
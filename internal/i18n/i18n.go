@@ -0,0 +1,52 @@
+package i18n
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Message identifies a user-facing string in the catalogs.
+type Message string
+
+const defaultLanguage = "en"
+
+var (
+	catalogs = map[string]map[Message]string{
+		defaultLanguage: english,
+	}
+	current = english
+)
+
+// SetLanguage selects the catalog messages are rendered from. It accepts plain language codes as well as locale
+// strings like those in LANG, e.g. de_DE.UTF-8. Languages without a catalog fall back to English.
+func SetLanguage(lang string) {
+	lang = strings.ToLower(lang)
+	if i := strings.IndexAny(lang, ".@"); i != -1 {
+		lang = lang[:i]
+	}
+	if i := strings.IndexAny(lang, "_-"); i != -1 {
+		lang = lang[:i]
+	}
+
+	catalog, ok := catalogs[lang]
+	if !ok {
+		catalog = catalogs[defaultLanguage]
+	}
+	current = catalog
+}
+
+// T renders message in the selected language, formatting args into it the same way as fmt.Sprintf. Messages missing
+// from the selected catalog fall back to English.
+func T(message Message, args ...interface{}) string {
+	format, ok := current[message]
+	if !ok {
+		format, ok = english[message]
+	}
+	if !ok {
+		format = string(message)
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
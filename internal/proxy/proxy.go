@@ -0,0 +1,63 @@
+// Package proxy routes the outbound HTTP requests of the upgrader, to GitHub and to the chart repositories, through
+// an explicitly configured proxy instead of the one in the environment.
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Config overrides the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables. The zero Config leaves the
+// environment in charge, net/http and helm's getters honor it by default.
+type Config struct {
+	// URL is the proxy for both http and https requests, e.g. http://proxy.example.com:3128.
+	URL string
+	// NoProxy is a comma separated list of hosts, domains and CIDRs reached directly, in NO_PROXY's format. Empty
+	// means NO_PROXY.
+	NoProxy string
+}
+
+// IsSet reports whether c overrides the environment.
+func (c Config) IsSet() bool {
+	return c.URL != "" || c.NoProxy != ""
+}
+
+// Func returns the proxy to use for a request, nil meaning a direct connection.
+func (c Config) Func() (func(*http.Request) (*url.URL, error), error) {
+	config := httpproxy.FromEnvironment()
+	if c.URL != "" {
+		proxyURL, err := url.Parse(c.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy [%s]: %w", c.URL, err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("invalid proxy [%s], the scheme must be one of: http, https, socks5", c.URL)
+		}
+		config.HTTPProxy = c.URL
+		config.HTTPSProxy = c.URL
+	}
+	if c.NoProxy != "" {
+		config.NoProxy = c.NoProxy
+	}
+
+	proxyFunc := config.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}, nil
+}
+
+// Transport returns a copy of http.DefaultTransport using the proxy of c.
+func (c Config) Transport() (*http.Transport, error) {
+	proxyFunc, err := c.Func()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFunc
+	return transport, nil
+}
@@ -0,0 +1,124 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// clearProxyEnvironment leaves the proxy to the tested Config.
+func clearProxyEnvironment(t *testing.T) {
+	for _, name := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy", "NO_PROXY", "no_proxy", "REQUEST_METHOD"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestConfigFunc(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    Config
+		env       map[string]string
+		requestTo string
+		// want is the proxy the request goes through, empty for a direct connection
+		want    string
+		wantErr string
+	}{
+		{name: "http", config: Config{URL: "http://proxy.example.com:3128"}, requestTo: "http://releases.rancher.com/server-charts/stable/index.yaml", want: "http://proxy.example.com:3128"},
+		{name: "https", config: Config{URL: "http://proxy.example.com:3128"}, requestTo: "https://api.github.com/repos/rancher/rancher/releases/tags/v2.7.5", want: "http://proxy.example.com:3128"},
+		{name: "socks5", config: Config{URL: "socks5://proxy.example.com:1080"}, requestTo: "https://api.github.com/", want: "socks5://proxy.example.com:1080"},
+		{name: "no proxy host", config: Config{URL: "http://proxy.example.com:3128", NoProxy: "api.github.com"}, requestTo: "https://api.github.com/", want: ""},
+		{name: "no proxy domain", config: Config{URL: "http://proxy.example.com:3128", NoProxy: ".rancher.com"}, requestTo: "https://releases.rancher.com/", want: ""},
+		{name: "no proxy other host", config: Config{URL: "http://proxy.example.com:3128", NoProxy: "api.github.com"}, requestTo: "https://releases.rancher.com/", want: "http://proxy.example.com:3128"},
+		{
+			name:      "overrides the environment",
+			config:    Config{URL: "http://proxy.example.com:3128"},
+			env:       map[string]string{"HTTPS_PROXY": "http://env-proxy.example.com:8080"},
+			requestTo: "https://api.github.com/",
+			want:      "http://proxy.example.com:3128",
+		},
+		{
+			name:      "no proxy overrides the environment",
+			config:    Config{NoProxy: "api.github.com"},
+			env:       map[string]string{"HTTPS_PROXY": "http://env-proxy.example.com:8080", "NO_PROXY": "releases.rancher.com"},
+			requestTo: "https://releases.rancher.com/",
+			want:      "http://env-proxy.example.com:8080",
+		},
+		{
+			name:      "unset leaves the environment",
+			env:       map[string]string{"HTTPS_PROXY": "http://env-proxy.example.com:8080"},
+			requestTo: "https://api.github.com/",
+			want:      "http://env-proxy.example.com:8080",
+		},
+		{name: "unsupported scheme", config: Config{URL: "ftp://proxy.example.com"}, wantErr: "invalid proxy [ftp://proxy.example.com], the scheme must be one of: http, https, socks5"},
+		{name: "invalid url", config: Config{URL: "http://proxy example.com"}, wantErr: "invalid proxy [http://proxy example.com]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearProxyEnvironment(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			proxyFunc, err := tt.config.Func()
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Fatalf("Func() error = %v, want it to start with %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Func() unexpected error: %v", err)
+			}
+			req, err := http.NewRequest(http.MethodGet, tt.requestTo, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			proxyURL, err := proxyFunc(req)
+			if err != nil {
+				t.Fatalf("proxy func unexpected error: %v", err)
+			}
+			got := ""
+			if proxyURL != nil {
+				got = proxyURL.String()
+			}
+			if got != tt.want {
+				t.Errorf("proxy for %s = %q, want %q", tt.requestTo, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransportThroughStubProxy(t *testing.T) {
+	clearProxyEnvironment(t)
+	var proxied []string
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// requests to a proxy carry the absolute URL of their destination
+		proxied = append(proxied, r.URL.String())
+		io.WriteString(w, "via proxy")
+	}))
+	defer stub.Close()
+
+	transport, err := Config{URL: stub.URL}.Transport()
+	if err != nil {
+		t.Fatalf("Transport() unexpected error: %v", err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Get("http://releases.rancher.com/server-charts/stable/index.yaml")
+	if err != nil {
+		t.Fatalf("request through the proxy failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(body) != "via proxy" {
+		t.Errorf("response body = %q, want the stub proxy's", body)
+	}
+	if len(proxied) != 1 || proxied[0] != "http://releases.rancher.com/server-charts/stable/index.yaml" {
+		t.Errorf("stub proxy received %v, want the index request", proxied)
+	}
+}
@@ -1 +1,55 @@
 package releasenotes
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Release is the subset of a GitHub release API response the release notes are built from.
+type Release struct {
+	TagName     string    `json:"tag_name"`
+	HTMLURL     string    `json:"html_url"`
+	Body        string    `json:"body"`
+	Draft       bool      `json:"draft"`
+	Prerelease  bool      `json:"prerelease"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+var requiredFields = []string{"tag_name", "html_url", "body"}
+
+// Parse decodes a GitHub release API response. GitHub reports failures such as unknown tags or rate limiting as a
+// JSON object carrying only a message, those are returned as errors rather than as a release with empty notes.
+func Parse(data []byte) (*Release, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("github release response is not a JSON object: %w", err)
+	}
+
+	if _, ok := fields["tag_name"]; !ok {
+		if message, ok := fields["message"]; ok {
+			var errorMessage string
+			if err := json.Unmarshal(message, &errorMessage); err == nil {
+				return nil, fmt.Errorf("github returned an error instead of a release: %s", errorMessage)
+			}
+		}
+	}
+
+	var missing []string
+	for _, field := range requiredFields {
+		if _, ok := fields[field]; !ok {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) != 0 {
+		return nil, fmt.Errorf("github release response is missing fields [%s]", strings.Join(missing, ", "))
+	}
+
+	// body is null for releases published without notes, which decodes to an empty string
+	release := &Release{}
+	if err := json.Unmarshal(data, release); err != nil {
+		return nil, fmt.Errorf("failed to decode github release response: %w", err)
+	}
+	return release, nil
+}
@@ -0,0 +1,97 @@
+package releasenotes
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    *Release
+		wantErr string
+	}{
+		{
+			name: "release",
+			data: `{"tag_name": "v2.7.5", "html_url": "https://github.com/rancher/rancher/releases/tag/v2.7.5", "body": "# Notes",
+				"draft": false, "prerelease": true, "published_at": "2023-06-29T18:00:00Z"}`,
+			want: &Release{
+				TagName:     "v2.7.5",
+				HTMLURL:     "https://github.com/rancher/rancher/releases/tag/v2.7.5",
+				Body:        "# Notes",
+				Prerelease:  true,
+				PublishedAt: time.Date(2023, 6, 29, 18, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "null body",
+			data: `{"tag_name": "v2.7.5", "html_url": "https://github.com/rancher/rancher/releases/tag/v2.7.5", "body": null}`,
+			want: &Release{TagName: "v2.7.5", HTMLURL: "https://github.com/rancher/rancher/releases/tag/v2.7.5"},
+		},
+		{
+			name:    "not found",
+			data:    `{"message": "Not Found", "documentation_url": "https://docs.github.com/rest/releases/releases"}`,
+			wantErr: "github returned an error instead of a release: Not Found",
+		},
+		{
+			name:    "rate limited",
+			data:    `{"message": "API rate limit exceeded for 203.0.113.7."}`,
+			wantErr: "github returned an error instead of a release: API rate limit exceeded for 203.0.113.7.",
+		},
+		{
+			name:    "message that is not a string",
+			data:    `{"message": {"text": "Not Found"}}`,
+			wantErr: "github release response is missing fields [tag_name, html_url, body]",
+		},
+		{
+			name:    "missing fields",
+			data:    `{"tag_name": "v2.7.5"}`,
+			wantErr: "github release response is missing fields [html_url, body]",
+		},
+		{
+			name:    "truncated",
+			data:    `{"tag_name": "v2.7.5", "html_url": `,
+			wantErr: "github release response is not a JSON object",
+		},
+		{
+			name:    "array",
+			data:    `[{"tag_name": "v2.7.5"}]`,
+			wantErr: "github release response is not a JSON object",
+		},
+		{
+			name:    "empty",
+			data:    ``,
+			wantErr: "github release response is not a JSON object",
+		},
+		{
+			name:    "html error page",
+			data:    `<html><body>502 Bad Gateway</body></html>`,
+			wantErr: "github release response is not a JSON object",
+		},
+		{
+			name:    "wrong field type",
+			data:    `{"tag_name": 275, "html_url": "", "body": ""}`,
+			wantErr: "failed to decode github release response",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			if *got != *tt.want {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// Package support describes which rancher minor versions are still supported and which rancher-webhook versions they
+// work with.
+package support
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+// bundledMatrix is the support matrix shipped with the tool, taken from the SUSE Rancher support lifecycle. It ages
+// with the binary, so a newer matrix can be passed with a file instead.
+//
+//go:embed matrix.json
+var bundledMatrix []byte
+
+// Line is a rancher minor version line and the date its support ends. EndOfLife is empty while no date is announced.
+// Webhook is the range of rancher-webhook chart versions the line works with, e.g. ">=2.0.0 <3.0.0", empty when
+// unknown.
+type Line struct {
+	Minor     string `json:"minor"`
+	EndOfLife string `json:"endOfLife,omitempty"`
+	Webhook   string `json:"webhook,omitempty"`
+}
+
+// Matrix lists the support lifecycle of the rancher minor versions.
+type Matrix struct {
+	Lines []Line `json:"lines"`
+}
+
+// Load reads the matrix from the JSON file at path, or the bundled matrix when path is empty.
+func Load(path string) (Matrix, error) {
+	data := bundledMatrix
+	if path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return Matrix{}, err
+		}
+	}
+
+	var matrix Matrix
+	if err := json.Unmarshal(data, &matrix); err != nil {
+		return Matrix{}, fmt.Errorf("failed to parse support matrix: %w", err)
+	}
+	return matrix, nil
+}
+
+// EndOfLife returns the date support ends for the minor line of version. It returns false when the line is not in the
+// matrix or has no announced date.
+func (m Matrix) EndOfLife(version string) (time.Time, bool, error) {
+	line, err := m.line(version)
+	if err != nil || line == nil || line.EndOfLife == "" {
+		return time.Time{}, false, err
+	}
+	endOfLife, err := time.Parse("2006-01-02", line.EndOfLife)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid end of life date [%s] for rancher [%s] in support matrix: %w", line.EndOfLife, line.Minor, err)
+	}
+	return endOfLife, true, nil
+}
+
+// Webhook returns the range of rancher-webhook chart versions the minor line of version works with, along with the
+// range as written in the matrix. The written range is empty when the line has no webhook requirement.
+func (m Matrix) Webhook(version string) (semver.Range, string, error) {
+	line, err := m.line(version)
+	if err != nil || line == nil || line.Webhook == "" {
+		return nil, "", err
+	}
+	webhookRange, err := semver.ParseRange(line.Webhook)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid rancher-webhook range [%s] for rancher [%s] in support matrix: %w", line.Webhook, line.Minor, err)
+	}
+	return webhookRange, line.Webhook, nil
+}
+
+// line returns the minor line of version, nil when it is not in the matrix.
+func (m Matrix) line(version string) (*Line, error) {
+	parsed, err := semver.Parse(version)
+	if err != nil {
+		return nil, err
+	}
+
+	minor := fmt.Sprintf("%d.%d", parsed.Major, parsed.Minor)
+	for i := range m.Lines {
+		if m.Lines[i].Minor == minor {
+			return &m.Lines[i], nil
+		}
+	}
+	return nil, nil
+}
@@ -1,7 +1,13 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
 	"github.com/rmweir/rancher-upgrader/cmd"
+	"github.com/rmweir/rancher-upgrader/internal/cleanup"
+	"github.com/rmweir/rancher-upgrader/internal/helm"
+	"github.com/rmweir/rancher-upgrader/internal/i18n"
 	"github.com/urfave/cli/v2"
 	"log"
 	"os"
@@ -11,12 +17,43 @@ func main() {
 	app := &cli.App{
 		Name:  "rancher-upgrade",
 		Usage: "Upgrade rancher release and inform user on critical changes",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "lang",
+				Usage:   "Language for prompts and messages, e.g. en",
+				EnvVars: []string{"LANG"},
+			},
+			&cli.BoolFlag{
+				Name:  "no-color",
+				Usage: "Disable colored output, which is also disabled by NO_COLOR or when the output is not a terminal",
+			},
+		},
+		Before: func(ctx *cli.Context) error {
+			i18n.SetLanguage(ctx.String("lang"))
+			if ctx.Bool("no-color") {
+				color.NoColor = true
+			}
+			cleanup.HandleSignals(i18n.T(i18n.CleaningUp), os.Exit)
+			return nil
+		},
 	}
 
 	app.Commands = []*cli.Command{
 		cmd.UpgradeCommand(),
+		cmd.ValuesCommand(),
+		cmd.CompareCommand(),
+		cmd.ValidateCommand(),
+		cmd.SetupCommand(),
+		cmd.ListCommand(),
+		cmd.HistoryCommand(),
+		cmd.RollbackCommand(),
+		cmd.DebugCommand(),
 	}
 	if err := app.Run(os.Args); err != nil {
+		var missingRepo *helm.MissingRepoError
+		if errors.As(err, &missingRepo) {
+			fmt.Fprintf(os.Stderr, "\n%s\n\n    %s\n\n", i18n.T(i18n.MissingRepoRemediation), missingRepo.Remediation())
+		}
 		log.Fatal(err)
 	}
 }